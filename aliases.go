@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// queryAliases are shorthands for types that can be used in queries,
+// eg: `( ctx, str ) -> ( err )`. More can be added in the alias file.
+var queryAliases = map[string]string{
+	"ctx": "context.Context",
+	"err": "error",
+	"str": "string",
+	"bs":  "[]byte",
+	"req": "*http.Request",
+	"rw":  "http.ResponseWriter",
+	"dur": "time.Duration",
+}
+
+func defaultAliasFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "glee", "aliases")
+}
+
+// loadQueryAliases adds the aliases in path to queryAliases. Every line
+// of the file is an alias like `str=string`, empty lines and lines
+// starting with `#` are ignored. A missing file is not an error.
+func loadQueryAliases(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, t, ok := strings.Cut(line, "=")
+		name, t = strings.TrimSpace(name), strings.TrimSpace(t)
+		if !ok || name == "" || t == "" {
+			return fmt.Errorf("%s:%d: invalid alias, expected name=type", path, n)
+		}
+		queryAliases[name] = t
+	}
+
+	return scanner.Err()
+}
+
+// expandQueryAliases replaces the aliases in a query with their types.
+// Argument labels (names followed by a `:`) are left as they are.
+func expandQueryAliases(uinput string) string {
+	out := ""
+	last := 0
+	for _, loc := range typeName.FindAllStringIndex(uinput, -1) {
+		t, ok := queryAliases[uinput[loc[0]:loc[1]]]
+		if !ok || strings.HasPrefix(uinput[loc[1]:], ":") {
+			continue
+		}
+		out += uinput[last:loc[0]] + t
+		last = loc[1]
+	}
+	return out + uinput[last:]
+}
@@ -0,0 +1,150 @@
+package main
+
+import "strings"
+
+// quoteSyntax describes a kind of string or character literal
+type quoteSyntax struct {
+	open, close string
+
+	escape  bool // a backslash escapes the next character
+	doubled bool // a doubled closing quote is part of the literal ('it''s')
+	oneLine bool // a newline ends the literal (character literals)
+	keep    bool // the content is skipped but not blanked (Erlang quoted atoms)
+
+	// notAfter reports if the quote after prev is not the start of a
+	// literal (eg: the adjoint in Julia's `x'`)
+	notAfter func(prev byte) bool
+}
+
+// lexicalSyntax are the comments and literals of a language that the
+// parsers without a tree-sitter grammar have to skip
+type lexicalSyntax struct {
+	lineComments  []string
+	blockComments [][2]string
+	nested        bool // block comments can be nested
+	shebang       bool // the first line can be a `#!` line
+	quotes        []quoteSyntax
+
+	// keepQuotes keeps the delimiters of literals and only blanks
+	// their content
+	keepQuotes bool
+
+	// literal returns the length of a language specific literal at the
+	// start of src (like SQL's dollar quoted strings), or 0
+	literal func(src string) int
+}
+
+// blankCommentsAndStrings replaces the comments and literals of syntax
+// in src with spaces (keeping newlines and offsets) so that they
+// cannot be mistaken for code
+func blankCommentsAndStrings(src string, syntax lexicalSyntax) string {
+	out := []byte(src)
+	blank := func(from, to int) {
+		for i := from; i < to && i < len(out); i++ {
+			if out[i] != '\n' {
+				out[i] = ' '
+			}
+		}
+	}
+
+	i := 0
+	if syntax.shebang && strings.HasPrefix(src, "#!") {
+		i = strings.IndexByte(src, '\n')
+		if i == -1 {
+			i = len(src)
+		}
+		blank(0, i)
+	}
+
+	// blankLiteral blanks the literal in src[start:end] which has
+	// delimiters of the given lengths
+	blankLiteral := func(start, end, open, close int) {
+		if syntax.keepQuotes {
+			blank(start+open, end-close)
+		} else {
+			blank(start, end)
+		}
+	}
+
+scan:
+	for ; i < len(src); i++ {
+		for _, c := range syntax.blockComments {
+			if !strings.HasPrefix(src[i:], c[0]) {
+				continue
+			}
+			depth, j := 1, i+len(c[0])
+			for j < len(src) && depth > 0 {
+				switch {
+				case strings.HasPrefix(src[j:], c[1]):
+					depth--
+					j += len(c[1])
+				case syntax.nested && strings.HasPrefix(src[j:], c[0]):
+					depth++
+					j += len(c[0])
+				default:
+					j++
+				}
+			}
+			blank(i, j)
+			i = j - 1
+			continue scan
+		}
+
+		for _, c := range syntax.lineComments {
+			if !strings.HasPrefix(src[i:], c) {
+				continue
+			}
+			end := strings.IndexByte(src[i:], '\n')
+			if end == -1 {
+				end = len(src) - i
+			}
+			blank(i, i+end)
+			i += end
+			continue scan
+		}
+
+		if syntax.literal != nil {
+			if n := syntax.literal(src[i:]); n > 0 {
+				blankLiteral(i, i+n, 1, 0)
+				i += n - 1
+				continue
+			}
+		}
+
+		for _, q := range syntax.quotes {
+			if !strings.HasPrefix(src[i:], q.open) || i > 0 && q.notAfter != nil && q.notAfter(src[i-1]) {
+				continue
+			}
+
+			j := i + len(q.open)
+			for j < len(src) {
+				if strings.HasPrefix(src[j:], q.close) {
+					if !q.doubled || !strings.HasPrefix(src[j+len(q.close):], q.close) {
+						break
+					}
+					j += len(q.close)
+				} else if q.oneLine && src[j] == '\n' {
+					break
+				} else if q.escape && src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j > len(src) {
+				j = len(src)
+			}
+
+			end := j
+			if strings.HasPrefix(src[j:], q.close) {
+				end += len(q.close)
+			}
+			if !q.keep {
+				blankLiteral(i, end, len(q.open), end-j)
+			}
+			i = end - 1
+			continue scan
+		}
+	}
+
+	return string(out)
+}
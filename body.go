@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// defaultBodyLines is how many lines of the body -show-body shows
+// without a value
+const defaultBodyLines = 5
+
+// bodyLines is the value of -show-body, which can be used as a boolean
+// flag or with a number of lines (eg: -show-body=10)
+type bodyLines int
+
+func (b *bodyLines) String() string { return strconv.Itoa(int(*b)) }
+
+func (b *bodyLines) Set(s string) error {
+	switch s {
+	case "true":
+		*b = defaultBodyLines
+	case "false":
+		*b = 0
+	default:
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return fmt.Errorf("expected a number of lines")
+		}
+		*b = bodyLines(n)
+	}
+	return nil
+}
+
+func (b *bodyLines) IsBoolFlag() bool { return true }
+
+// bodyNodeRows is the first and last rows of body
+func bodyNodeRows(body *sitter.Node) []int {
+	start, end := body.StartPoint(), body.EndPoint()
+	// some bodies end with the newline after them
+	if end.Column == 0 && end.Row > start.Row {
+		end.Row--
+	}
+	return []int{int(start.Row), int(end.Row)}
+}
+
+// bodyRows is the first and last rows of the source from the offset
+// start to end (exclusive), for the parsers without a grammar
+func bodyRows(sourceCode []byte, start, end int) []int {
+	row := bytes.Count(sourceCode[:start], []byte("\n"))
+	last := max(start, end-1) // the newline ending the body is not in it
+	return []int{row, row + bytes.Count(sourceCode[start:last], []byte("\n"))}
+}
+
+// bodySnippet is the first n lines of the body of f without their
+// common indentation, ending with `...` if there are more of them. They
+// are read from the file like the lines of -C, as the functions do not
+// keep their source.
+func bodySnippet(f Func, n int) ([]string, error) {
+	if len(f.BodyLoc) < 2 || n == 0 {
+		return nil, nil
+	}
+
+	from, to := f.BodyLoc[0], f.BodyLoc[1]
+	truncated := to-from+1 > n
+	if truncated {
+		to = from + n - 1
+	}
+	lines, err := readLines(f.Path, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	indent := -1
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		if i := len(l) - len(strings.TrimLeft(l, " \t")); indent < 0 || i < indent {
+			indent = i
+		}
+	}
+
+	for i, l := range lines {
+		if len(l) >= indent && indent > 0 {
+			l = l[indent:]
+		}
+		lines[i] = strings.TrimRight(l, " \t\r")
+	}
+	if truncated {
+		lines = append(lines, "...")
+	}
+	return lines, nil
+}
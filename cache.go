@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// cacheVersion is changed along with the format of the cache entries
+const cacheVersion = 2
+
+// funcCache keeps the functions of every file parsed under a hash of its
+// content, so that the files that did not change since the last search
+// are not parsed again. The entries are in $XDG_CACHE_HOME/glee (or the
+// cache directory of the OS).
+type funcCache struct {
+	dir   string
+	salt  string // so that other builds of glee do not use the entries
+	index fileIndex
+}
+
+// fileIndex has the key of the functions of every file searched from a
+// directory along with its modification time and size when it was
+// parsed, so that the files that did not change are not even read again
+type fileIndex struct {
+	Salt  string
+	Files map[string]indexEntry
+}
+
+type indexEntry struct {
+	Language string
+	Version  string // of the language, see language.Version
+	ModTime  int64
+	Size     int64
+	Key      string
+}
+
+func newFuncCache() (*funcCache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	// a new build can extract the functions differently
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(exe)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := fmt.Sprintf("%d %d %d", cacheVersion, info.ModTime().UnixNano(), info.Size())
+	c := &funcCache{dir: filepath.Join(dir, "glee"), salt: salt}
+	c.loadIndex()
+	return c, nil
+}
+
+// key is the key of the functions of f with sourceCode, which includes
+// the path as it is stored in the functions and the version of the
+// grammar of external languages
+func (c *funcCache) key(f file, sourceCode []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00", c.salt, f.Language, languages[f.Language].Version, f.Path)
+	h.Write(sourceCode)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *funcCache) path(key string) string {
+	return filepath.Join(c.dir, "funcs", key[:2], key)
+}
+
+// get returns the functions stored under key, if there are any
+func (c *funcCache) get(key string) ([]Func, bool) {
+	file, err := os.Open(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	funcs := []Func{}
+	if err := gob.NewDecoder(bufio.NewReader(file)).Decode(&funcs); err != nil {
+		debugf(1, "ignoring the cache entry %s: %s", c.path(key), err)
+		return nil, false
+	}
+	return funcs, true
+}
+
+// put stores funcs under key, replacing the entry atomically so that
+// concurrent searches never read a partial one
+func (c *funcCache) put(key string, funcs []Func) error {
+	return writeGob(c.path(key), funcs)
+}
+
+// embeddings are the embeddings of the functions of a file (by the text
+// that was embedded) from an endpoint and model, stored along with the
+// functions of the file
+type embeddings struct {
+	URL     string
+	Model   string
+	Vectors map[string][]float64
+}
+
+func (c *funcCache) embeddingsPath(key string) string {
+	return filepath.Join(c.dir, "embeddings", key[:2], key)
+}
+
+// getEmbeddings returns the embeddings from e of the functions of the
+// file at path, if they are cached for its current content
+func (c *funcCache) getEmbeddings(path string, e embedder) map[string][]float64 {
+	entry, ok := c.index.Files[path]
+	if !ok {
+		return nil
+	}
+	file, err := os.Open(c.embeddingsPath(entry.Key))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	emb := embeddings{}
+	if err := gob.NewDecoder(bufio.NewReader(file)).Decode(&emb); err != nil {
+		debugf(1, "ignoring the cache entry %s: %s", c.embeddingsPath(entry.Key), err)
+		return nil
+	}
+	if emb.URL != e.URL || emb.Model != e.Model {
+		return nil
+	}
+	return emb.Vectors
+}
+
+// putEmbeddings stores vectors as the embeddings from e of the functions
+// of the file at path
+func (c *funcCache) putEmbeddings(path string, e embedder, vectors map[string][]float64) error {
+	entry, ok := c.index.Files[path]
+	if !ok {
+		return nil
+	}
+	return writeGob(c.embeddingsPath(entry.Key), embeddings{URL: e.URL, Model: e.Model, Vectors: vectors})
+}
+
+// writeGob writes v to path, replacing it atomically
+func writeGob(path string, v any) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	if err := gob.NewEncoder(w).Encode(v); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// openCache is the cache to use for loading the functions, nil with
+// noCache or if there is no cache directory
+func openCache(noCache bool) *funcCache {
+	if noCache {
+		return nil
+	}
+	c, err := newFuncCache()
+	if err != nil {
+		debugf(1, "not using the cache: %s", err)
+		return nil
+	}
+	return c
+}
+
+// indexPath is the index of the files searched from the working
+// directory, as their paths are relative to it
+func (c *funcCache) indexPath() (string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(wd))
+	return filepath.Join(c.dir, "index", hex.EncodeToString(sum[:])), nil
+}
+
+// loadIndex reads the index of the working directory, starting a new
+// one if there is none or it is from another build of glee
+func (c *funcCache) loadIndex() {
+	c.index = fileIndex{Salt: c.salt, Files: map[string]indexEntry{}}
+	path, err := c.indexPath()
+	if err != nil {
+		return
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	index := fileIndex{}
+	if err := gob.NewDecoder(bufio.NewReader(file)).Decode(&index); err != nil {
+		debugf(1, "ignoring the cache index %s: %s", path, err)
+		return
+	}
+	if index.Salt == c.salt && index.Files != nil {
+		c.index = index
+	}
+}
+
+// unchanged returns the key of the functions of f if it has the same
+// modification time and size (from info) as when it was parsed, with
+// the same version of its language
+func (c *funcCache) unchanged(f file, info os.FileInfo) (string, bool) {
+	e, ok := c.index.Files[f.Path]
+	if !ok || e.Language != f.Language || e.Version != languages[f.Language].Version || e.ModTime != info.ModTime().UnixNano() || e.Size != info.Size() {
+		return "", false
+	}
+	return e.Key, true
+}
+
+// saveIndex updates the index with the files parsed (and their new
+// keys) and writes it. The files in the index that do not exist anymore
+// are removed from it along with their functions (and embeddings), and
+// so are the old ones of the files that changed.
+func (c *funcCache) saveIndex(parsed map[string]indexEntry) error {
+	pruned := 0
+	for path, e := range c.index.Files {
+		if n, ok := parsed[path]; ok {
+			if n.Key != e.Key {
+				os.Remove(c.path(e.Key))
+				os.Remove(c.embeddingsPath(e.Key))
+			}
+			continue
+		}
+		if _, err := os.Lstat(path); errors.Is(err, fs.ErrNotExist) {
+			os.Remove(c.path(e.Key))
+			os.Remove(c.embeddingsPath(e.Key))
+			delete(c.index.Files, path)
+			pruned++
+		}
+	}
+	for path, e := range parsed {
+		c.index.Files[path] = e
+	}
+	if pruned > 0 {
+		debugf(1, "removed %d deleted files from the cache", pruned)
+	}
+
+	path, err := c.indexPath()
+	if err != nil {
+		return err
+	}
+	return writeGob(path, c.index)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	colorReset     = "\033[0m"
+	colorPath      = "\033[35m"
+	colorLocation  = "\033[32m"
+	colorName      = "\033[1m"
+	colorHighlight = "\033[1;31m"
+	colorDoc       = "\033[2m"
+)
+
+// useColor decides if the output should be colored for -color mode
+// (auto, always or never). auto colors the output of terminals unless
+// NO_COLOR is set.
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		return stdoutIsTerminal(), nil
+	}
+	return false, fmt.Errorf("invalid color mode '%s' (options: auto, always, never)", mode)
+}
+
+func stdoutIsTerminal() bool {
+	stat, err := os.Stdout.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+func stderrIsTerminal() bool {
+	stat, err := os.Stderr.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+func stdinIsTerminal() bool {
+	stat, err := os.Stdin.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+var highlightToken = regexp.MustCompile(`[\w$]+`)
+
+// highlights returns the names of the types in queries (but not the
+// excluded ones), which are highlighted in the signatures of the results
+func highlights(queries []string) map[string]bool {
+	names := map[string]bool{}
+	for _, q := range queries {
+		_, q = splitNameQuery(q)
+		_, q = splitReceiverQuery(q)
+		q, _, _ = splitExclusions(expandQueryAliases(q))
+		for _, t := range highlightToken.FindAllString(q+" "+normalizeType(q), -1) {
+			names[t] = true
+		}
+	}
+	return names
+}
+
+// colorize is the location and the description of f in the text format
+// with colors, highlighting the names in its signature that are in the
+// query
+func colorize(f Func, names map[string]bool) (string, string) {
+	highlight := func(types []string) string {
+		return highlightToken.ReplaceAllStringFunc(strings.Join(types, ", "), func(t string) string {
+			if names[t] || names[normalizeType(t)] {
+				return colorHighlight + t + colorReset
+			}
+			return t
+		})
+	}
+
+	location := fmt.Sprintf("%s%s%s:%s%d:%d%s", colorPath, f.Path, colorReset, colorLocation, f.Loc[0], f.Loc[1], colorReset)
+	description := fmt.Sprintf("%s%s%s (%s) -> (%s)", colorName, f.FullName(), colorReset, highlight(f.LabelledArgs()), highlight(f.Rets))
+	return location, description
+}
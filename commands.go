@@ -0,0 +1,191 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// subcommands are the commands of glee with a description, `glee QUERY`
+// is the same as `glee search QUERY`
+var subcommands = [][2]string{
+	{"search", "search for functions by their signature (the default)"},
+	{"tags", "write the functions to a tags file"},
+	{"stats", "show how many files and functions there are in every language"},
+	{"index", "parse the files ahead of time so that the next searches use the cache"},
+	{"serve", "keep the functions in memory and answer searches over HTTP"},
+	{"completion", "print the shell completion script for bash, zsh or fish"},
+}
+
+// isSubcommand checks if name is one of the subcommands
+func isSubcommand(name string) bool {
+	for _, c := range subcommands {
+		if c[0] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceOptions are the options of the subcommands that parse all the
+// functions without searching them
+type sourceOptions struct {
+	walk     walkOptions
+	grammars string
+	langs    string
+	quiet    bool
+	jobs     int
+	noCache  bool
+}
+
+// sourceFlags defines the flags of o in fs
+func sourceFlags(fs *flag.FlagSet, o *sourceOptions) {
+	walkFlags(fs, &o.walk)
+	fs.StringVar(&o.grammars, "grammars", defaultGrammarsDir(), "directory to load external grammars from")
+	fs.StringVar(&o.langs, "lang", "", "comma separated list of languages to include (eg: go,typescript)")
+	fs.BoolVar(&o.quiet, "q", false, "do not show the progress of parsing the files")
+	fs.IntVar(&o.jobs, "j", 0, "number of files to parse at once (0 for one per CPU)")
+	verboseFlags(fs)
+}
+
+// load finds the files in roots and parses their functions, using cache
+// if it is not nil
+func (o sourceOptions) load(roots []string, cache *funcCache) ([]file, []Func, error) {
+	if err := loadGrammars(o.grammars); err != nil {
+		return nil, nil, err
+	}
+	if o.langs != "" {
+		if err := selectLanguages(strings.Split(o.langs, ",")); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	files, err := findAllFiles(roots, o.walk)
+	if err != nil {
+		return nil, nil, err
+	}
+	funcs, err := loadFuncs(files, nil, o.quiet, o.jobs, cache, nil)
+	return files, funcs, err
+}
+
+// parseFlags parses the arguments of a subcommand with fs and, like for
+// the search, sets the options that were not passed from the environment
+// and then from the config files
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	fs.Parse(args)
+	if err := loadEnv(fs); err != nil {
+		return err
+	}
+	return loadConfig(fs, defaultConfigFile(), projectConfigFile())
+}
+
+// statsFlags defines the flags of `glee stats` that set o
+func statsFlags(o *sourceOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	sourceFlags(fs, o)
+	fs.BoolVar(&o.noCache, "no-cache", false, "parse all the files again instead of using the cached functions")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s stats [OPTIONS] [path...]\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(os.Stderr, "Show how many files and functions there are in every language")
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// stats is `glee stats`, which counts the files and functions (and the
+// ones in test files) by language, to check what glee finds
+func stats(args []string) error {
+	o := sourceOptions{}
+	fs := statsFlags(&o)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	roots := fs.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	files, funcs, err := o.load(roots, openCache(o.noCache))
+	if err != nil {
+		return err
+	}
+
+	type count struct{ files, funcs, tests int }
+	counts := map[string]*count{}
+	total := count{files: len(files), funcs: len(funcs)}
+	languageOf := map[string]string{}
+	for _, f := range files {
+		if counts[f.Language] == nil {
+			counts[f.Language] = &count{}
+		}
+		counts[f.Language].files++
+		languageOf[f.Path] = f.Language
+	}
+	for _, f := range funcs {
+		c := counts[languageOf[f.Path]]
+		c.funcs++
+		if f.Test {
+			c.tests++
+			total.tests++
+		}
+	}
+
+	langs := sortedKeys(counts)
+	sort.SliceStable(langs, func(i, j int) bool { return counts[langs[i]].funcs > counts[langs[j]].funcs })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "language\tfiles\tfunctions\ttests")
+	for _, lang := range langs {
+		c := counts[lang]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", languageTitle(lang), c.files, c.funcs, c.tests)
+	}
+	fmt.Fprintf(w, "total\t%d\t%d\t%d\n", total.files, total.funcs, total.tests)
+	return w.Flush()
+}
+
+// indexFlags defines the flags of `glee index` that set o
+func indexFlags(o *sourceOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("index", flag.ExitOnError)
+	sourceFlags(fs, o)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s index [OPTIONS] [path...]\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(os.Stderr, "Parse the files ahead of time so that the next searches use the cache")
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// index is `glee index`, which parses the files that changed into the
+// cache (eg: in a git hook) so that searching from the same directory
+// does not have to
+func index(args []string) error {
+	o := sourceOptions{}
+	fs := indexFlags(&o)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	roots := fs.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	cache, err := newFuncCache()
+	if err != nil {
+		return fmt.Errorf("cannot find the cache directory: %w", err)
+	}
+	files, funcs, err := o.load(roots, cache)
+	if err != nil {
+		return err
+	}
+
+	if !o.quiet {
+		fmt.Printf("indexed %d functions in %d files\n", len(funcs), len(files))
+	}
+	return nil
+}
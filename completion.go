@@ -0,0 +1,266 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// shells are the shells `glee completion` can write a script for, from
+// the flags of every subcommand
+var shells = map[string]func(w io.Writer, flags map[string]*flag.FlagSet){
+	"bash": writeBashCompletion,
+	"zsh":  writeZshCompletion,
+	"fish": writeFishCompletion,
+}
+
+// shellNames are the names of the shells, for the completions of
+// `glee completion` itself
+var shellNames = []string{"bash", "fish", "zsh"}
+
+// positionals is what the arguments of the subcommands are
+var positionals = map[string]string{"search": "signature or path", "tags": "path", "stats": "path", "index": "path", "serve": "path"}
+
+// flagCompletion is how the value of a flag is completed
+type flagCompletion struct {
+	values func() []string
+	list   bool // comma separated values (eg: -lang go,rust)
+	file   bool
+	dir    bool
+}
+
+// fixedValues completes the values of a flag with values
+func fixedValues(values ...string) func() []string {
+	return func() []string { return values }
+}
+
+// flagCompletions are the flags (of the search and of the subcommands)
+// whose values can be completed, other values are not completed
+var flagCompletions = map[string]flagCompletion{
+	"match":      {values: fixedValues(matchModes...)},
+	"o":          {values: outputNames},
+	"sort":       {values: func() []string { return sortedKeys(sortings) }},
+	"group-by":   {values: func() []string { return sortedKeys(groupings) }},
+	"metric":     {values: func() []string { return sortedKeys(metrics) }},
+	"color":      {values: fixedValues("auto", "always", "never")},
+	"hyperlinks": {values: fixedValues("auto", "always", "never")},
+	"lang":       {values: languageNames, list: true},
+	"aliases":    {file: true},
+	"files-from": {file: true},
+	"f":          {file: true},
+	"grammars":   {dir: true},
+}
+
+// completion is `glee completion <shell>`, which prints the completion
+// script of the shell for the flags in search and in the subcommands
+func completion(args []string, search *flag.FlagSet) error {
+	if len(args) != 1 || shells[args[0]] == nil {
+		return fmt.Errorf("usage: glee completion %s", strings.Join(shellNames, "|"))
+	}
+
+	// the languages of the external grammars are completed too
+	if err := loadGrammars(defaultGrammarsDir()); err != nil {
+		return err
+	}
+	flags := map[string]*flag.FlagSet{
+		"search": search,
+		"tags":   tagsFlags(&tagsOptions{}),
+		"stats":  statsFlags(&sourceOptions{}),
+		"index":  indexFlags(&sourceOptions{}),
+		"serve":  serveFlags(&serveOptions{}),
+	}
+	shells[args[0]](os.Stdout, flags)
+	return nil
+}
+
+// commandNames are the names of the subcommands separated by sep
+func commandNames(sep string) string {
+	names := []string{}
+	for _, c := range subcommands {
+		names = append(names, c[0])
+	}
+	return strings.Join(names, sep)
+}
+
+// isBoolFlag checks if f does not take a value, including the ones
+// with an optional value like -show-body
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// flagNames are the names of the flags in fs with a dash
+func flagNames(fs *flag.FlagSet) string {
+	names := []string{}
+	fs.VisitAll(func(f *flag.Flag) { names = append(names, "-"+f.Name) })
+	return strings.Join(names, " ")
+}
+
+func writeBashCompletion(w io.Writer, flags map[string]*flag.FlagSet) {
+	// bashValues are the cases completing the values of the flags in fs
+	bashValues := func(fs *flag.FlagSet) string {
+		var sb strings.Builder
+		others := []string{}
+		fs.VisitAll(func(f *flag.Flag) {
+			if isBoolFlag(f) {
+				return
+			}
+			c := flagCompletions[f.Name]
+			pattern := fmt.Sprintf("-%s|--%s", f.Name, f.Name)
+			switch {
+			case c.list:
+				fmt.Fprintf(&sb, "            %s) COMPREPLY=($(compgen -P \"${cur%%\"${cur##*,}\"}\" -W \"%s\" -- \"${cur##*,}\")); compopt -o nospace; return;;\n", pattern, strings.Join(c.values(), " "))
+			case c.values != nil:
+				fmt.Fprintf(&sb, "            %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return;;\n", pattern, strings.Join(c.values(), " "))
+			case c.file:
+				fmt.Fprintf(&sb, "            %s) COMPREPLY=($(compgen -f -- \"$cur\")); return;;\n", pattern)
+			case c.dir:
+				fmt.Fprintf(&sb, "            %s) COMPREPLY=($(compgen -d -- \"$cur\")); return;;\n", pattern)
+			default:
+				others = append(others, pattern)
+			}
+		})
+		if len(others) > 0 {
+			fmt.Fprintf(&sb, "            %s) return;;\n", strings.Join(others, "|"))
+		}
+		return sb.String()
+	}
+
+	var cases strings.Builder
+	for _, c := range subcommands {
+		if fs := flags[c[0]]; fs != nil {
+			fmt.Fprintf(&cases, "    %s)\n        case $prev in\n%s        esac\n        flags=\"%s\";;\n", c[0], bashValues(fs), flagNames(fs))
+		}
+	}
+
+	fmt.Fprintf(w, `# bash completion for glee, eg: source <(glee completion bash)
+_glee() {
+    local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}
+    local cmd=search flags=
+    if [[ $COMP_CWORD -gt 1 ]]; then
+        case ${COMP_WORDS[1]} in
+        %[1]s) cmd=${COMP_WORDS[1]};;
+        esac
+    fi
+
+    case $cmd in
+    completion)
+        COMPREPLY=($(compgen -W "%[2]s" -- "$cur"))
+        return;;
+%[3]s    esac
+
+    if [[ $cur == -* ]]; then
+        COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+    elif [[ $COMP_CWORD -eq 1 ]]; then
+        COMPREPLY=($(compgen -W "%[4]s" -- "$cur") $(compgen -f -- "$cur"))
+    else
+        COMPREPLY=($(compgen -f -- "$cur"))
+    fi
+}
+complete -o filenames -F _glee glee
+`, commandNames("|"), strings.Join(shellNames, " "), cases.String(), commandNames(" "))
+}
+
+func writeZshCompletion(w io.Writer, flags map[string]*flag.FlagSet) {
+	quote := strings.NewReplacer(`'`, `'\''`, `[`, `\[`, `]`, `\]`)
+
+	// zshSpecs are the specs of the flags in fs for _arguments
+	zshSpecs := func(fs *flag.FlagSet) string {
+		var sb strings.Builder
+		fs.VisitAll(func(f *flag.Flag) {
+			fmt.Fprintf(&sb, "            '-%s[%s]", f.Name, quote.Replace(f.Usage))
+			if !isBoolFlag(f) {
+				c := flagCompletions[f.Name]
+				switch {
+				case c.list:
+					fmt.Fprintf(&sb, ":%s:_sequence compadd - %s", f.Name, strings.Join(c.values(), " "))
+				case c.values != nil:
+					fmt.Fprintf(&sb, ":%s:(%s)", f.Name, strings.Join(c.values(), " "))
+				case c.file:
+					fmt.Fprintf(&sb, ":%s:_files", f.Name)
+				case c.dir:
+					fmt.Fprintf(&sb, ":%s:_files -/", f.Name)
+				default:
+					fmt.Fprintf(&sb, ":%s: ", f.Name)
+				}
+			}
+			sb.WriteString("' \\\n")
+		})
+		return sb.String()
+	}
+
+	commands := []string{}
+	var cases strings.Builder
+	for _, c := range subcommands {
+		commands = append(commands, fmt.Sprintf("'%s:%s'", c[0], quote.Replace(c[1])))
+		if fs := flags[c[0]]; fs != nil {
+			fmt.Fprintf(&cases, "    %s)\n        _arguments -S \\\n%s            '*:%s:_files';;\n", c[0], zshSpecs(fs), positionals[c[0]])
+		}
+	}
+
+	fmt.Fprintf(w, `#compdef glee
+# zsh completion for glee, eg: glee completion zsh > "${fpath[1]}/_glee"
+_glee() {
+    local cmd=search
+    if (( CURRENT > 2 )) && [[ $words[2] == (%[1]s) ]]; then
+        cmd=$words[2]
+        shift words
+        (( CURRENT-- ))
+    elif (( CURRENT == 2 )); then
+        local -a commands=(%[2]s)
+        _describe -t commands command commands
+    fi
+
+    case $cmd in
+    completion)
+        (( CURRENT == 2 )) && _values shell %[3]s;;
+%[4]s    esac
+}
+_glee "$@"
+`, commandNames("|"), strings.Join(commands, " "), strings.Join(shellNames, " "), cases.String())
+}
+
+func writeFishCompletion(w io.Writer, flags map[string]*flag.FlagSet) {
+	quote := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+
+	// fishFlags completes the flags in fs when condition is true
+	fishFlags := func(fs *flag.FlagSet, condition string) {
+		fs.VisitAll(func(f *flag.Flag) {
+			option := "-o " + f.Name
+			if len(f.Name) == 1 {
+				option = "-s " + f.Name
+			}
+			fmt.Fprintf(w, "complete -c glee -n '%s' %s -d '%s'", condition, option, quote.Replace(f.Usage))
+			if !isBoolFlag(f) {
+				c := flagCompletions[f.Name]
+				switch {
+				case c.values != nil:
+					fmt.Fprintf(w, " -x -a '%s'", strings.Join(c.values(), " "))
+				case c.file:
+					fmt.Fprint(w, " -r -F")
+				case c.dir:
+					fmt.Fprint(w, " -x -a '(__fish_complete_directories)'")
+				default:
+					fmt.Fprint(w, " -x")
+				}
+			}
+			fmt.Fprintln(w)
+		})
+	}
+
+	fmt.Fprintln(w, "# fish completion for glee, eg: glee completion fish > ~/.config/fish/completions/glee.fish")
+	for _, c := range subcommands {
+		fmt.Fprintf(w, "complete -c glee -n '__fish_use_subcommand' -a %s -d '%s'\n", c[0], quote.Replace(c[1]))
+	}
+	fmt.Fprintf(w, "complete -c glee -n '__fish_seen_subcommand_from completion' -f -a '%s'\n", strings.Join(shellNames, " "))
+	for _, c := range subcommands {
+		if fs := flags[c[0]]; fs != nil && c[0] != "search" {
+			fishFlags(fs, "__fish_seen_subcommand_from "+c[0])
+		}
+	}
+	// the search is also the default command
+	others := strings.ReplaceAll(commandNames(" "), "search ", "")
+	fishFlags(flags["search"], "not __fish_seen_subcommand_from "+others)
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// projectConfigName is the name of the config file of a project, which
+// is looked for in the working directory and the ones above it
+const projectConfigName = ".glee.toml"
+
+func defaultConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "glee", "config.toml")
+}
+
+// projectConfigFile returns the closest .glee.toml, if there is one
+func projectConfigFile() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, projectConfigName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		if dir == filepath.Dir(dir) {
+			return ""
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// envNames are the environment variables of the options that are not
+// named after them: GLEE_FORMAT is the output format (-o) while the
+// template of -format is GLEE_TEMPLATE
+var envNames = map[string]string{"o": "GLEE_FORMAT", "format": "GLEE_TEMPLATE"}
+
+// envLists are the options whose environment variables are comma
+// separated lists, unlike queries (-e) that can contain commas
+var envLists = map[string]bool{"exclude": true, "include": true}
+
+// envName is the environment variable of an option, eg: GLEE_NO_TESTS
+// for -no-tests
+func envName(option string) string {
+	if name, ok := envNames[option]; ok {
+		return name
+	}
+	return "GLEE_" + strings.ToUpper(strings.ReplaceAll(option, "-", "_"))
+}
+
+// loadEnv sets the options that were not passed as flags from their
+// environment variables (see envName). The globs of GLEE_EXCLUDE and
+// GLEE_INCLUDE are comma separated.
+func loadEnv(fs *flag.FlagSet) error {
+	passed := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { passed[f.Name] = true })
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok || passed[f.Name] || err != nil {
+			return
+		}
+
+		values := []string{value}
+		if envLists[f.Name] {
+			values = strings.Split(value, ",")
+		}
+		for _, v := range values {
+			if e := fs.Set(f.Name, v); e != nil {
+				err = fmt.Errorf("%s: %w", envName(f.Name), e)
+				return
+			}
+		}
+	})
+	return err
+}
+
+// loadConfig sets the options in the config files (in order, so that
+// later ones win) that were not passed as flags or set in the
+// environment. The keys are the
+// names of the options, eg:
+//
+//	match = "includes"
+//	exclude = ["vendor/**", "*.pb.go"]
+//	lang = ["go", "typescript"]
+//
+// An `[aliases]` table adds query aliases like the alias file. Missing
+// files are not an error.
+func loadConfig(fs *flag.FlagSet, paths ...string) error {
+	passed := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { passed[f.Name] = true })
+
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+
+		config := map[string]any{}
+		if _, err := toml.DecodeFile(path, &config); os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		for key, value := range config {
+			if aliases, ok := value.(map[string]any); ok && key == "aliases" {
+				for name, t := range aliases {
+					queryAliases[name] = fmt.Sprint(t)
+				}
+				continue
+			}
+
+			f := fs.Lookup(key)
+			if f == nil && fs != flag.CommandLine && flag.CommandLine.Lookup(key) != nil {
+				continue // an option of the search that the subcommand does not have
+			}
+			if f == nil {
+				return fmt.Errorf("%s: unknown option '%s'", path, key)
+			}
+			if passed[key] {
+				continue
+			}
+
+			values := []string{fmt.Sprint(value)}
+			if list, ok := value.([]any); ok {
+				values = []string{}
+				for _, v := range list {
+					values = append(values, fmt.Sprint(v))
+				}
+				// lists of flags that cannot be repeated are comma
+				// separated (eg: -lang)
+				if _, ok := f.Value.(*stringList); !ok {
+					values = []string{strings.Join(values, ",")}
+				}
+			}
+
+			for _, v := range values {
+				if err := fs.Set(key, v); err != nil {
+					return fmt.Errorf("%s: %s: %w", path, key, err)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestLoadEnv(t *testing.T) {
+	var expressions, exclude stringList
+	fs := flag.NewFlagSet("glee", flag.ContinueOnError)
+	fs.Var(&expressions, "e", "")
+	fs.Var(&exclude, "exclude", "")
+	output := fs.String("o", "text", "")
+	format := fs.String("format", "", "")
+	match := fs.String("match", "default", "")
+	noTests := fs.Bool("no-tests", false, "")
+
+	t.Setenv("GLEE_E", "(int, string) -> error")
+	t.Setenv("GLEE_EXCLUDE", "vendor/**,*.pb.go")
+	t.Setenv("GLEE_FORMAT", "grep")
+	t.Setenv("GLEE_TEMPLATE", "{{.Name}}")
+	t.Setenv("GLEE_MATCH", "includes")
+	t.Setenv("GLEE_NO_TESTS", "true")
+
+	// flags win over the environment
+	if err := fs.Parse([]string{"-match", "superset"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadEnv(fs); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (stringList{"(int, string) -> error"}); !reflect.DeepEqual(expressions, want) {
+		t.Errorf("GLEE_E set -e to %q, want %q", expressions, want)
+	}
+	if want := (stringList{"vendor/**", "*.pb.go"}); !reflect.DeepEqual(exclude, want) {
+		t.Errorf("GLEE_EXCLUDE set -exclude to %q, want %q", exclude, want)
+	}
+	if *output != "grep" {
+		t.Errorf("GLEE_FORMAT set -o to %q, want grep", *output)
+	}
+	if *format != "{{.Name}}" {
+		t.Errorf("GLEE_TEMPLATE set -format to %q, want {{.Name}}", *format)
+	}
+	if *match != "superset" {
+		t.Errorf("GLEE_MATCH overrode -match superset with %q", *match)
+	}
+	if !*noTests {
+		t.Error("GLEE_NO_TESTS did not set -no-tests")
+	}
+}
+
+func TestLoadEnvError(t *testing.T) {
+	fs := flag.NewFlagSet("glee", flag.ContinueOnError)
+	fs.Int("j", 0, "")
+	t.Setenv("GLEE_J", "many")
+
+	if err := loadEnv(fs); err == nil {
+		t.Error("loadEnv accepted GLEE_J=many")
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+)
+
+// contextLines reads the n lines around row (0 based) from path, as
+// `line: text` for row and `line- text` for the others like grep -C.
+// The files are only read when the results are printed so that the
+// sources do not have to be kept around.
+func contextLines(path string, row, n int) ([]string, error) {
+	from := max(row-n, 0)
+	lines, err := readLines(path, from, row+n)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, l := range lines {
+		sep := "-"
+		if from+i == row {
+			sep = ":"
+		}
+		lines[i] = fmt.Sprintf("%d%s %s", from+i+1, sep, l)
+	}
+	return lines, nil
+}
+
+// readLines reads the rows from to to (0 based, included) of the file
+// at path, there can be fewer of them at the end of the file
+func readLines(path string, from, to int) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	lines := []string{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for i := 0; i <= to && scanner.Scan(); i++ {
+		if i >= from {
+			lines = append(lines, scanner.Text())
+		}
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// dartCandidate matches a name (with optional generics) followed
+	// by the opening paren of a parameter list
+	dartCandidate = regexp.MustCompile(`([A-Za-z_$][\w$]*)\s*(<[^(){};=]*>)?\s*\(`)
+
+	// dartAnnotation matches annotations like @override or @Deprecated('')
+	dartAnnotation = regexp.MustCompile(`@[\w.]+(\([^)]*\))?`)
+
+	// dartType matches what can be a return type (including function types)
+	dartType = regexp.MustCompile(`^[\w$<>,?. \[\]]*(Function\s*(<[^>]*>)?\([^)]*\)\??)?$`)
+)
+
+// dartKeywords can be followed by a paren, but do not start a function
+var dartKeywords = map[string]bool{
+	"if": true, "for": true, "while": true, "switch": true, "catch": true,
+	"return": true, "await": true, "new": true, "throw": true, "else": true,
+	"yield": true, "case": true, "assert": true, "super": true, "this": true,
+	"do": true, "in": true, "is": true, "as": true, "sync": true, "async": true,
+}
+
+// dartModifiers are dropped from the return type
+var dartModifiers = map[string]bool{
+	"static": true, "external": true, "abstract": true, "const": true,
+	"factory": true, "final": true, "late": true, "covariant": true,
+	"required": true, "var": true,
+}
+
+// parseDart finds functions and methods in Dart source. There is no
+// tree-sitter grammar for Dart available to us, so declarations are
+// found by looking for `type name(params)` followed by a body (`{` or
+// `=>`) or, for abstract methods, a `;`. Named parameters are labelled
+// and parameters or results without a type are `_`.
+func parseDart(sourceCode []byte, path string) []Func {
+	funcs := []Func{}
+	src := blankCommentsAndStrings(string(sourceCode), dartSyntax)
+	lines := strings.Split(string(sourceCode), "\n")
+
+	for _, m := range dartCandidate.FindAllStringSubmatchIndex(src, -1) {
+		name := src[m[2]:m[3]]
+		if dartKeywords[name] {
+			continue
+		}
+
+		// method calls and named constructors
+		before := strings.TrimRight(src[:m[2]], " \t\r\n")
+		if strings.HasSuffix(before, ".") {
+			continue
+		}
+
+		open := m[1] - 1
+		end := matchingBracket(src, open)
+		if end == -1 {
+			continue
+		}
+
+		after := strings.TrimLeft(src[end+1:], " \t\r\n")
+		for _, kw := range []string{"async*", "async", "sync*"} {
+			if strings.HasPrefix(after, kw) {
+				after = strings.TrimLeft(after[len(kw):], " \t\r\n")
+				break
+			}
+		}
+
+		ret, ok := dartReturnType(before)
+		if !ok {
+			continue
+		}
+
+		bodyStart, bodyEnd := len(src)-len(after), -1
+		switch {
+		case strings.HasPrefix(after, "{"):
+			bodyEnd = matchingBracket(src, bodyStart)
+		case strings.HasPrefix(after, "=>"):
+			bodyEnd = dartExpressionEnd(src, bodyStart)
+		case strings.HasPrefix(after, ";") && ret != "":
+		default:
+			continue
+		}
+
+		f := Func{
+			Path:   path,
+			Loc:    position(sourceCode, dartStart(src, m[2], ret)),
+			Name:   name,
+			Args:   []string{},
+			Labels: []string{},
+			Rets:   []string{"_"},
+		}
+		if ret != "" {
+			f.Rets = []string{ret}
+		}
+		f.Doc = commentAbove(lines, f.Loc[0], []string{"//"}, "/*", "*/")
+		if bodyEnd != -1 {
+			f.BodyLoc = bodyRows(sourceCode, bodyStart, bodyEnd+1)
+		}
+
+		dartParams(src[open+1:end], "", &f)
+		funcs = append(funcs, f)
+	}
+
+	return funcs
+}
+
+// dartExpressionEnd returns the offset of the `;` ending the body of an
+// arrow function starting at start, or -1
+func dartExpressionEnd(src string, start int) int {
+	depth := 0
+	for i := start; i < len(src); i++ {
+		switch src[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case ';':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// dartReturnType finds the return type in the text before a function
+// name, dropping annotations and modifiers. It reports false if the
+// text is not the start of a declaration (eg: an assignment).
+func dartReturnType(before string) (string, bool) {
+	start := strings.LastIndexAny(before, ";{}") + 1
+	decl := before[start:]
+
+	decl = dartAnnotation.ReplaceAllString(decl, " ")
+
+	parts := []string{}
+	for _, p := range strings.Fields(decl) {
+		if !dartModifiers[p] {
+			parts = append(parts, p)
+		}
+	}
+	ret := strings.Join(parts, " ")
+
+	if !dartType.MatchString(ret) || dartKeywords[ret] || ret == "get" || ret == "set" {
+		return "", false
+	}
+	return ret, true
+}
+
+// dartStart is the offset where the declaration of a function named at
+// offset nameStart with return type ret starts
+func dartStart(src string, nameStart int, ret string) int {
+	if ret == "" {
+		return nameStart
+	}
+	if i := strings.LastIndex(src[:nameStart], ret); i != -1 {
+		return i
+	}
+	return nameStart
+}
+
+// dartParams adds the parameters in text to f, labelling them if they
+// are named parameters
+func dartParams(text string, group string, f *Func) {
+	for _, p := range splitTopLevel(text, ",") {
+		p = strings.TrimSpace(p)
+		switch {
+		case p == "":
+			continue
+		case strings.HasPrefix(p, "{"):
+			dartParams(strings.TrimSuffix(p[1:], "}"), "{", f)
+			continue
+		case strings.HasPrefix(p, "["):
+			dartParams(strings.TrimSuffix(p[1:], "]"), "[", f)
+			continue
+		}
+
+		// default values
+		if i := strings.IndexAny(p, "=:"); i != -1 {
+			p = strings.TrimSpace(p[:i])
+		}
+
+		fields := []string{}
+		for _, w := range strings.Fields(p) {
+			if !dartModifiers[w] && !strings.HasPrefix(w, "@") {
+				fields = append(fields, w)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+
+		name := fields[len(fields)-1]
+		t := strings.Join(fields[:len(fields)-1], " ")
+		if t == "" {
+			t = "_"
+		}
+		name = strings.TrimPrefix(strings.TrimPrefix(name, "this."), "super.")
+
+		label := ""
+		if group == "{" {
+			label = name
+		}
+
+		f.Args = append(f.Args, t)
+		f.Labels = append(f.Labels, label)
+	}
+}
+
+// dartSyntax are the comments and string literals of Dart
+var dartSyntax = lexicalSyntax{
+	lineComments:  []string{"//"},
+	blockComments: [][2]string{{"/*", "*/"}},
+	quotes: []quoteSyntax{
+		{open: `"""`, close: `"""`, escape: true},
+		{open: "'''", close: "'''", escape: true},
+		{open: `"`, close: `"`, escape: true},
+		{open: "'", close: "'", escape: true},
+	},
+	keepQuotes: true,
+}
+
+// matchingBracket returns the offset of the bracket closing the one
+// (`(`, `[` or `{`) at open
+func matchingBracket(src string, open int) int {
+	close := map[byte]byte{'(': ')', '[': ']', '{': '}'}[src[open]]
+
+	depth := 0
+	for i := open; i < len(src); i++ {
+		switch src[i] {
+		case src[open]:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// position converts a byte offset into a (0 based) row and column,
+// followed by the offset itself
+func position(sourceCode []byte, offset int) []int {
+	row := strings.Count(string(sourceCode[:offset]), "\n")
+	col := offset - (strings.LastIndex(string(sourceCode[:offset]), "\n") + 1)
+	return []int{row, col, offset}
+}
+
+// span is the position of start followed by the row and column of end,
+// like the Loc of functions parsed with tree-sitter
+func span(sourceCode []byte, start, end int) []int {
+	return append(position(sourceCode, start), position(sourceCode, end)[:2]...)
+}
@@ -0,0 +1,599 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// signatureToken matches the atoms of a signature: names, `->`, `[]`
+// and single punctuation characters like `*` or `,`
+var signatureToken = regexp.MustCompile(`->|\[\]|[\w$]+|[^\s\w$]`)
+
+// tokenize splits a signature like `( []string, int ) -> ( *Foo )` into
+// its atoms, eg: `(`, `[]`, `string`, `,`, `int`, `)`, `->`, ...
+func tokenize(signature string) []string {
+	return signatureToken.FindAllString(signature, -1)
+}
+
+// tokenDistance is the distance (levenshtein by default) between two
+// signatures computed over their tokens instead of their characters so
+// that a type is either the same or different: `string` is as far from
+// `strong` as it is from `int`. Names are weighed by opts.TokenWeights
+// (see tokenWeights), everything else costs 1.
+func tokenDistance(a, b string, opts searchOptions) float64 {
+	if opts.IgnoreCase {
+		a, b = strings.ToLower(a), strings.ToLower(b)
+	}
+
+	cost := func(t string) float64 {
+		if w, ok := opts.TokenWeights[t]; ok {
+			return w
+		}
+		return 1
+	}
+
+	distance := opts.Metric
+	if distance == nil {
+		distance = editDistance
+	}
+	return distance(tokenize(a), tokenize(b), cost)
+}
+
+// editDistance is the levenshtein distance between two lists of atoms
+// where inserting or deleting an atom costs cost(atom) and replacing
+// one the average of the two costs
+func editDistance(ta, tb []string, cost func(string) float64) float64 {
+	prev := make([]float64, len(tb)+1)
+	curr := make([]float64, len(tb)+1)
+	for j := 1; j <= len(tb); j++ {
+		prev[j] = prev[j-1] + cost(tb[j-1])
+	}
+
+	for i := 1; i <= len(ta); i++ {
+		curr[0] = prev[0] + cost(ta[i-1])
+		for j := 1; j <= len(tb); j++ {
+			replace := 0.0
+			if ta[i-1] != tb[j-1] {
+				replace = (cost(ta[i-1]) + cost(tb[j-1])) / 2
+			}
+			curr[j] = min(prev[j]+cost(ta[i-1]), curr[j-1]+cost(tb[j-1]), prev[j-1]+replace)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(tb)]
+}
+
+// minTokenWeight is the weight of names used by every function so
+// that they still count a little
+const minTokenWeight = 0.1
+
+// tokenWeights computes the inverse document frequency of the names in
+// the signatures of funcs, scaled to between minTokenWeight (in every
+// signature) and 1 (in none) so that common types like `error` affect
+// the distance less than rare ones
+func tokenWeights(funcs []Func, opts searchOptions) map[string]float64 {
+	df := map[string]int{}
+	for _, f := range funcs {
+		if opts.Normalize {
+			f = f.Normalized()
+		}
+
+		signature := f.LabelledSignature()
+		if opts.IgnoreCase {
+			signature = strings.ToLower(signature)
+		}
+
+		seen := map[string]bool{}
+		for _, t := range tokenize(signature) {
+			if !seen[t] && typeName.MatchString(t) {
+				seen[t] = true
+				df[t]++
+			}
+		}
+	}
+
+	n := math.Log(float64(len(funcs) + 1))
+	tw := map[string]float64{}
+	for t, c := range df {
+		tw[t] = max(minTokenWeight, math.Log(float64(len(funcs)+1)/float64(c+1))/n)
+	}
+	return tw
+}
+
+// nameQuery matches the name part of a query like `parse : ( []byte ) -> ( Config )`
+var nameQuery = regexp.MustCompile(`^\s*([\w$.]+)\s*:\s*(\(|->)`)
+
+// splitNameQuery splits a query into the name of the function (if
+// there is one) and its signature
+func splitNameQuery(uinput string) (string, string) {
+	m := nameQuery.FindStringSubmatchIndex(uinput)
+	if m == nil {
+		return "", uinput
+	}
+	return uinput[m[2]:m[3]], uinput[m[4]:]
+}
+
+// receiverQuery matches the receiver part of a query like
+// `(*Server) ( context.Context ) -> ( error )`
+var receiverQuery = regexp.MustCompile(`^\s*\(\s*([^()]*?)\s*\)\s*\(`)
+
+// splitReceiverQuery splits a query into the type of the receiver (if
+// there is one, without pointers) and the signature
+func splitReceiverQuery(uinput string) (string, string) {
+	m := receiverQuery.FindStringSubmatchIndex(uinput)
+	if m == nil {
+		return "", uinput
+	}
+	return strings.TrimLeft(uinput[m[2]:m[3]], "*&"), uinput[m[1]-1:]
+}
+
+// receiverDistance is how far the receiver of f is from receiver, like
+// nameDistance
+func receiverDistance(receiver string, f Func) int {
+	return nameDistance(receiver, Func{Name: strings.TrimLeft(f.Receiver, "*&")})
+}
+
+// nameDistance is how far the name of f is from name, ignoring case:
+// 0 if it is the same, 1 if it contains name (`parse` in
+// `parseConfig`) and the levenshtein distance of the characters
+// otherwise. Names with a `.` are compared with the receiver.
+func nameDistance(name string, f Func) int {
+	fname := f.Name
+	if strings.Contains(name, ".") {
+		fname = f.FullName()
+	}
+
+	name, fname = strings.ToLower(name), strings.ToLower(fname)
+	switch {
+	case name == fname:
+		return 0
+	case strings.Contains(fname, name):
+		return 1
+	}
+	unit := func(string) float64 { return 1 }
+	return int(editDistance(strings.Split(name, ""), strings.Split(fname, ""), unit))
+}
+
+// fuzzyContains checks if the tokens of test are in item, with up to
+// typos edits in every name. Names get one edit for every 4 characters
+// so that short ones (eg: `io`) do not match anything.
+func fuzzyContains(item, test string, typos int) bool {
+	ti, tt := tokenize(item), tokenize(test)
+	unit := func(string) float64 { return 1 }
+
+	for start := 0; start+len(tt) <= len(ti); start++ {
+		match := true
+		for i, t := range tt {
+			it := ti[start+i]
+			if it == t {
+				continue
+			}
+
+			allowed := min(typos, len(t)/4)
+			if !bareName.MatchString(t) || editDistance(strings.Split(t, ""), strings.Split(it, ""), unit) > float64(allowed) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// reorderArgs moves the arguments of f that are in inputs to the same
+// position as in inputs (filling the other positions with the rest of
+// the arguments) so that the order of arguments does not affect the
+// distance, and reports if that changed the order
+func (f Func) reorderArgs(inputs []string, labelled bool) (Func, bool) {
+	args := f.Args
+	if labelled {
+		args = f.LabelledArgs()
+	}
+
+	used := make([]bool, len(args))
+	slots := make([]int, len(inputs))
+	for p, in := range inputs {
+		slots[p] = -1
+		for i, a := range args {
+			if !used[i] && a == in {
+				used[i] = true
+				slots[p] = i
+				break
+			}
+		}
+	}
+
+	rest := []int{}
+	for i := range args {
+		if !used[i] {
+			rest = append(rest, i)
+		}
+	}
+
+	order := []int{}
+	for _, i := range slots {
+		if i == -1 {
+			if len(rest) == 0 {
+				continue
+			}
+			i, rest = rest[0], rest[1:]
+		}
+		order = append(order, i)
+	}
+	order = append(order, rest...)
+
+	reordered := false
+	newArgs, newLabels := []string{}, []string{}
+	for k, i := range order {
+		reordered = reordered || k != i
+		newArgs = append(newArgs, f.Args[i])
+		if i < len(f.Labels) {
+			newLabels = append(newLabels, f.Labels[i])
+		}
+	}
+
+	f.Args, f.Labels = newArgs, newLabels
+	return f, reordered
+}
+
+// wrapper matches the pointers, slices and varargs around a type
+var wrapper = regexp.MustCompile(`\*|\[\]|\.\.\.`)
+
+// stripWrappers removes pointers, slices and varargs from types, eg:
+// `*Config`, `[]Config` and `...Config` all become `Config`
+func stripWrappers(types []string) []string {
+	stripped := []string{}
+	for _, t := range types {
+		stripped = append(stripped, strings.TrimSpace(wrapper.ReplaceAllString(t, "")))
+	}
+	return stripped
+}
+
+// isWildcard checks if a type in a query is a placeholder for any type
+func isWildcard(t string) bool {
+	return t == "_" || t == "?"
+}
+
+// fillWildcards replaces the wildcards in the inputs and outputs of a
+// query with the types of f at the same position and returns the
+// resulting query signature
+func fillWildcards(inputs, outputs []string, f Func, labelled bool) string {
+	args := f.Args
+	if labelled {
+		args = f.LabelledArgs()
+	}
+
+	fill := func(query, types []string) []string {
+		filled := []string{}
+		for i, q := range query {
+			if isWildcard(q) && i < len(types) {
+				q = types[i]
+			}
+			filled = append(filled, q)
+		}
+		return filled
+	}
+
+	return fmt.Sprintf("( %s ) -> ( %s )",
+		strings.Join(fill(inputs, args), ", "),
+		strings.Join(fill(outputs, f.Rets), ", "))
+}
+
+// paramQuery matches a query type with a parameter name, eg: `path:string`
+var paramQuery = regexp.MustCompile(`^([A-Za-z_$][\w$]*):([^:\s].*)$`)
+
+var bareName = regexp.MustCompile(`^[A-Za-z_$][\w$]*$`)
+
+// withParamNames replaces the arguments of f whose parameter names are
+// used in the query with the names, so that `path` matches a `path
+// string` argument, and `path:string` only matches a `string` if the
+// parameter is called path
+func (f Func) withParamNames(inputs []string, ignoreCase bool) Func {
+	if len(f.Params) != len(f.Args) {
+		return f
+	}
+
+	equal := func(a, b string) bool {
+		return a == b || (ignoreCase && strings.EqualFold(a, b))
+	}
+
+	args := append([]string{}, f.Args...)
+	for i, p := range f.Params {
+		if p == "" {
+			continue
+		}
+
+		for _, q := range inputs {
+			if m := paramQuery.FindStringSubmatch(q); m != nil && equal(m[1], p) {
+				args[i] = m[1] + ":" + args[i]
+				break
+			}
+			if bareName.MatchString(q) && equal(q, p) && !equal(q, args[i]) {
+				args[i] = q
+				break
+			}
+		}
+	}
+
+	f.Args = args
+	return f
+}
+
+// unify replaces the type parameters of a generic function with the
+// types at the same place in the query, eg: `T` with `int` when
+// matching `( []T ) -> ( T )` against `( []int ) -> ( int )`, so that a
+// generic function is as close to a query as its instantiation. A type
+// parameter is bound to the first type it lines up with.
+func (f Func) unify(inputs, outputs []string, labelled bool) Func {
+	bound, _ := f.bindings(inputs, outputs, labelled)
+	if len(bound) == 0 {
+		return f
+	}
+
+	substitute := func(types []string) []string {
+		out := []string{}
+		for _, t := range types {
+			out = append(out, substituteTypeParams(t, bound))
+		}
+		return out
+	}
+
+	f.Args, f.Rets = substitute(f.Args), substitute(f.Rets)
+	return f
+}
+
+// Costs of the type parameters bound by unify when ranking: a generic
+// function is a little further from a query than its instantiation,
+// and a type parameter that is only bound by the outputs (eg: `T` of
+// `func Zero[T any]() T`) could be any type, so it is as far from the
+// query as a different type would be
+const (
+	boundTypeParamCost  = 0.1
+	outputTypeParamCost = 1
+)
+
+// unifyCost returns how much binding the type parameters of f to the
+// query (see unify) adds to the distance of its inputs and outputs
+func (f Func) unifyCost(inputs, outputs []string, labelled bool) (float64, float64) {
+	bound, byInputs := f.bindings(inputs, outputs, labelled)
+
+	var args, rets float64
+	for p := range bound {
+		if byInputs[p] {
+			args += boundTypeParamCost
+		} else {
+			rets += outputTypeParamCost
+		}
+	}
+	return args, rets
+}
+
+// bindings binds the type parameters of f to the types at the same
+// place in the query, the inputs first. It returns the bound types and
+// which of the type parameters were bound by the inputs.
+func (f Func) bindings(inputs, outputs []string, labelled bool) (map[string]string, map[string]bool) {
+	bound, byInputs := map[string]string{}, map[string]bool{}
+	if len(f.TypeParams) == 0 {
+		return bound, byInputs
+	}
+
+	params := map[string]bool{}
+	for _, p := range f.TypeParams {
+		params[p] = true
+	}
+
+	args := f.Args
+	if labelled {
+		args = f.LabelledArgs()
+	}
+
+	for i := 0; i < len(args) && i < len(inputs); i++ {
+		bindTypeParams(tokenize(args[i]), inputs[i], params, bound)
+	}
+	for p := range bound {
+		byInputs[p] = true
+	}
+	for i := 0; i < len(f.Rets) && i < len(outputs); i++ {
+		bindTypeParams(tokenize(f.Rets[i]), outputs[i], params, bound)
+	}
+	return bound, byInputs
+}
+
+// bindTypeParams walks the tokens of a type of the function (ft) and
+// of the query type q together, binding every type parameter that is
+// not bound yet to the part of q at its place. It stops at the first
+// token that does not line up.
+func bindTypeParams(ft []string, q string, params map[string]bool, bound map[string]string) {
+	locs := signatureToken.FindAllStringIndex(q, -1)
+	qt := []string{}
+	for _, loc := range locs {
+		qt = append(qt, q[loc[0]:loc[1]])
+	}
+
+	j := 0
+	for i := 0; i < len(ft) && j < len(qt); i++ {
+		if !params[ft[i]] {
+			if ft[i] != qt[j] {
+				return
+			}
+			j++
+			continue
+		}
+
+		next := ""
+		if i+1 < len(ft) {
+			next = ft[i+1]
+		}
+		end := typeSpan(qt, j, next)
+		if end == -1 {
+			return
+		}
+
+		t := q[locs[j][0]:locs[end-1][1]]
+		if _, ok := bound[ft[i]]; !ok && !isWildcard(t) {
+			bound[ft[i]] = t
+		}
+		j = end
+	}
+}
+
+// typeSpan returns where the type starting at the token start ends,
+// which is at next (if not nested within brackets) or at the end of the
+// tokens if there is no next. It returns -1 if the type does not end
+// there.
+func typeSpan(tokens []string, start int, next string) int {
+	depth := 0
+	for k := start; k < len(tokens); k++ {
+		if depth == 0 && k > start && tokens[k] == next {
+			return k
+		}
+
+		switch tokens[k] {
+		case "(", "[", "{", "<":
+			depth++
+		case ")", "]", "}", ">":
+			// `=>` is not a bracket
+			if tokens[k] == ">" && k > 0 && tokens[k-1] == "=" {
+				continue
+			}
+			depth--
+			if depth < 0 {
+				return -1
+			}
+		}
+	}
+
+	if next != "" {
+		return -1
+	}
+	return len(tokens)
+}
+
+// substituteTypeParams replaces the bound type parameters in t
+func substituteTypeParams(t string, bound map[string]string) string {
+	if len(bound) == 0 {
+		return t
+	}
+
+	out := ""
+	last := 0
+	for _, loc := range signatureToken.FindAllStringIndex(t, -1) {
+		if b, ok := bound[t[loc[0]:loc[1]]]; ok {
+			out += t[last:loc[0]] + b
+			last = loc[1]
+		}
+	}
+	return out + t[last:]
+}
+
+// weights are how much the distance of each part of a function counts
+// when ranking it
+type weights struct {
+	Name  float64 // distance of the name, for queries with a name
+	Args  float64 // distance of the arguments
+	Rets  float64 // distance of the results
+	Arity float64 // difference in the number of arguments and results
+}
+
+var defaultWeights = weights{Name: 1, Args: 1, Rets: 1, Arity: 0}
+
+// parseWeights parses weights like `rets=2,arity=0.5`, using the
+// default for the ones that are not set
+func parseWeights(s string) (weights, error) {
+	w := defaultWeights
+	if strings.TrimSpace(s) == "" {
+		return w, nil
+	}
+
+	fields := map[string]*float64{"name": &w.Name, "args": &w.Args, "rets": &w.Rets, "arity": &w.Arity}
+	for _, kv := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(kv, "=")
+		field, known := fields[strings.TrimSpace(k)]
+		if !ok || !known {
+			return w, fmt.Errorf("invalid weight '%s' (options: name, args, rets, arity)", kv)
+		}
+
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return w, fmt.Errorf("invalid weight '%s': %v", kv, err)
+		}
+		*field = f
+	}
+
+	return w, nil
+}
+
+// splitSignature splits a signature like `( a, b ) -> ( c )` into its
+// inputs `( a, b )` and outputs `( c )`
+func splitSignature(signature string) (string, string) {
+	parts := splitTopLevel(signature, "->")
+	if len(parts) != 2 {
+		return signature, ""
+	}
+	return parts[0], parts[1]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// variadicElem returns the type of the elements of a variadic
+// parameter like `...T` (or `T...`), and if t is variadic at all. C
+// style `...` is variadic of any type.
+func variadicElem(t string) (string, bool) {
+	switch {
+	case t == "...":
+		return "_", true
+	case strings.HasPrefix(t, "..."):
+		return strings.TrimSpace(t[3:]), true
+	case strings.HasSuffix(t, "..."):
+		return strings.TrimSpace(t[:len(t)-3]), true
+	}
+	return "", false
+}
+
+// expandVariadic repeats the element type of the variadic parameter of
+// f (if it has one as the last argument) as many times as needed for
+// the number of inputs, eg: `( string, ...any )` becomes `( string,
+// any, any )` for a query with three inputs or `( string )` for one.
+// Variadic parameters in the query are matched as they are.
+func (f Func) expandVariadic(inputs []string) Func {
+	if len(f.Args) == 0 || len(inputs) == 0 {
+		return f
+	}
+
+	last := len(f.Args) - 1
+	elem, ok := variadicElem(f.Args[last])
+	if !ok {
+		return f
+	}
+	if _, ok := variadicElem(inputs[len(inputs)-1]); ok && len(inputs) == len(f.Args) {
+		return f
+	}
+
+	n := len(nonEmpty(inputs)) - last
+	if n < 0 {
+		return f
+	}
+
+	args := append([]string{}, f.Args[:last]...)
+	labels := append([]string{}, f.Labels[:min(last, len(f.Labels))]...)
+	for i := 0; i < n; i++ {
+		args = append(args, elem)
+		if last < len(f.Labels) {
+			labels = append(labels, f.Labels[last])
+		}
+	}
+
+	f.Args, f.Labels = args, labels
+	return f
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// matchFuncs are the functions the matching modes are tested with
+var matchFuncs = []Func{
+	{Name: "Copy", Loc: []int{0, 0, 0}, Args: []string{"Writer", "Reader"}, Rets: []string{"int64", "error"}},
+	{Name: "Read", Loc: []int{1, 0, 0}, Args: []string{"[]byte"}, Rets: []string{"int", "error"}},
+	{Name: "ReadFull", Loc: []int{2, 0, 0}, Args: []string{"Reader", "[]byte"}, Rets: []string{"int", "error"}},
+	{Name: "Printf", Loc: []int{3, 0, 0}, Args: []string{"string", "...any"}, Rets: []string{"int", "error"}},
+	{Name: "Join", Loc: []int{4, 0, 0}, Args: []string{"string", "...string"}, Rets: []string{"string"}},
+	{Name: "Open", Loc: []int{5, 0, 0}, Args: []string{"string"}, Rets: []string{"*File", "error"}},
+}
+
+type matchTest struct {
+	name  string
+	query string
+	opts  searchOptions
+	want  []string // names of the functions found, closest first
+}
+
+// searchMatch searches matchFuncs for query with the match mode and
+// the default weights
+func searchMatch(t *testing.T, match, query string, opts searchOptions) []FuncWithDistance {
+	t.Helper()
+	opts.Quiet, opts.Weights = true, defaultWeights
+	fwd, err := search(matchFuncs, query, match, false, opts, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fwd
+}
+
+// distances maps the names of the functions found to their distance
+func distances(fwd []FuncWithDistance) map[string]float64 {
+	d := map[string]float64{}
+	for _, f := range fwd {
+		d[f.Func.Name] = f.Distance
+	}
+	return d
+}
+
+// testMatch checks the functions that search finds in matchFuncs with
+// the match mode for the queries of the tests
+func testMatch(t *testing.T, match string, tests []matchTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fwd := searchMatch(t, match, tt.query, tt.opts)
+			names := []string{}
+			for _, f := range fwd {
+				names = append(names, f.Func.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("search(%q) = %v, want %v", tt.query, names, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchSuperset(t *testing.T) {
+	testMatch(t, "superset", []matchTest{
+		{"extra arguments", "( Reader ) -> ( error )", searchOptions{}, []string{"Copy", "ReadFull"}},
+		{"any order", "( Reader, Writer ) ->", searchOptions{}, []string{"Copy"}},
+		{"results only", "-> ( error )", searchOptions{}, []string{"Read", "Open", "Copy", "ReadFull", "Printf"}},
+		{"wildcard", "( _, []byte ) ->", searchOptions{}, []string{"ReadFull"}},
+		{"variadic", "( string, string, string ) -> ( string )", searchOptions{}, []string{"Join"}},
+		{"variadic of another type", "( string, int, bool ) ->", searchOptions{}, []string{}},
+		{"every type once", "( Reader, Reader ) ->", searchOptions{}, []string{}},
+	})
+
+	// the distance is the number of extra arguments and results, not
+	// how far the types are from the query
+	tests := []struct {
+		query string
+		want  map[string]float64
+	}{
+		{"( []byte ) -> ( int, error )", map[string]float64{"Read": 0, "ReadFull": defaultWeights.Args}},
+		{"( Reader ) -> ( error )", map[string]float64{"Copy": defaultWeights.Args + defaultWeights.Rets, "ReadFull": defaultWeights.Args + defaultWeights.Rets}},
+		// variadic parameters can be left empty and are not extra
+		{"( string ) ->", map[string]float64{"Open": 2 * defaultWeights.Rets, "Join": defaultWeights.Rets, "Printf": 2 * defaultWeights.Rets}},
+	}
+	for _, tt := range tests {
+		if got := distances(searchMatch(t, "superset", tt.query, searchOptions{})); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("search(%q) found %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestMatchUnordered(t *testing.T) {
+	query := "( []byte, Reader ) -> ( int, error )"
+	if d := distances(searchMatch(t, "default", query, searchOptions{})); d["ReadFull"] == 0 {
+		t.Errorf("search(%q) found ReadFull at a distance of 0 with the arguments swapped", query)
+	}
+	if d := distances(searchMatch(t, "unordered", query, searchOptions{})); d["ReadFull"] != 0 {
+		t.Errorf("search(%q) found ReadFull at a distance of %v, want 0", query, d["ReadFull"])
+	}
+
+	// at the same distance the arguments in the order of the query
+	// come first
+	funcs := []Func{
+		{Name: "Swapped", Loc: []int{0, 0, 0}, Args: []string{"Reader", "Writer"}, Rets: []string{"error"}},
+		{Name: "InOrder", Loc: []int{1, 0, 0}, Args: []string{"Writer", "Reader"}, Rets: []string{"error"}},
+	}
+	fwd, err := search(funcs, "( Writer, Reader ) -> ( error )", "unordered", false, searchOptions{Weights: defaultWeights, Quiet: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fwd) != 2 || fwd[0].Func.Name != "InOrder" || fwd[0].Distance != 0 || fwd[1].Distance != 0 {
+		t.Errorf("search found %v, want InOrder and then Swapped, both at a distance of 0", fwd)
+	}
+}
+
+func TestMatchRegex(t *testing.T) {
+	testMatch(t, "regex", []matchTest{
+		{"whole types", `( byte ) ->`, searchOptions{}, []string{}},
+		{"every position", `( Read.* ) ->`, searchOptions{}, []string{}},
+		{"alternatives", `( (Writer|Reader), .* ) -> ( int(64)?, error )`, searchOptions{}, []string{"ReadFull", "Copy"}},
+		{"brackets", `( \[\]byte ) -> ( int, _ )`, searchOptions{}, []string{"Read"}},
+		{"ignore case", `( string ) -> ( \*file, ERROR )`, searchOptions{IgnoreCase: true}, []string{"Open"}},
+		{"variadic", `( string, \.\.\..* ) ->`, searchOptions{}, []string{"Printf", "Join"}},
+		{"anchored at the start", `( \[\]by ) -> ( int, error )`, searchOptions{}, []string{}},
+		{"anchored at the end", `( yte ) -> ( int, error )`, searchOptions{}, []string{}},
+		{"explicit prefix", `( .*yte ) -> ( int, error )`, searchOptions{}, []string{"Read"}},
+		{"results anchored", `( string ) -> ( \*Fil, error )`, searchOptions{}, []string{}},
+	})
+
+	// the other modes match the types literally
+	testMatch(t, "includes", []matchTest{
+		{"literal", `( Read.* ) ->`, searchOptions{}, []string{}},
+		{"brackets", `( []byte ) ->`, searchOptions{}, []string{"Read", "ReadFull"}},
+	})
+
+	if _, err := search(matchFuncs, `( [a- ) ->`, "regex", false, searchOptions{Quiet: true}, nil); err == nil {
+		t.Error("search with an invalid regular expression did not fail")
+	}
+}
+
+func TestExpandVariadic(t *testing.T) {
+	tests := []struct {
+		args   []string
+		inputs []string
+		want   []string
+	}{
+		{[]string{"string", "...any"}, []string{"string", "int", "bool"}, []string{"string", "any", "any"}},
+		{[]string{"string", "...any"}, []string{"string"}, []string{"string"}},
+		{[]string{"string", "...any"}, []string{"string", "...any"}, []string{"string", "...any"}},
+		{[]string{"char *", "..."}, []string{"char *", "int"}, []string{"char *", "_"}},
+		{[]string{"String..."}, []string{"String", "String"}, []string{"String", "String"}},
+		{[]string{"string", "int"}, []string{"string"}, []string{"string", "int"}},
+		{[]string{"string", "...any"}, []string{}, []string{"string", "...any"}},
+	}
+
+	for _, tt := range tests {
+		f := Func{Args: tt.args}.expandVariadic(tt.inputs)
+		if !reflect.DeepEqual(f.Args, tt.want) {
+			t.Errorf("expandVariadic(%v) of %v = %v, want %v", tt.inputs, tt.args, f.Args, tt.want)
+		}
+	}
+}
+
+func TestMatchVariadic(t *testing.T) {
+	testMatch(t, "includes", []matchTest{
+		{"elements", "( string, string, string ) -> ( string )", searchOptions{}, []string{"Join"}},
+		{"no elements", "( string ) -> ( string )", searchOptions{}, []string{"Join"}},
+		{"variadic query", "( string, ...any ) ->", searchOptions{}, []string{"Printf"}},
+	})
+
+	// the variadic parameter takes as many of the inputs as it has to
+	tests := []struct {
+		query string
+		exact bool
+		want  []string // names of the functions found at a distance of 0
+	}{
+		{"( string, any, any ) -> ( int, error )", false, []string{"Printf"}},
+		{"( string ) -> ( string )", false, []string{"Join"}},
+		{"( string, string, string, string ) -> ( string )", true, []string{"Join"}},
+		{"( string, ...string ) -> ( string )", true, []string{"Join"}},
+		{"( string, string ) -> ( string )", false, []string{"Join"}},
+	}
+	for _, tt := range tests {
+		fwd, err := search(matchFuncs, tt.query, "default", tt.exact, searchOptions{Weights: defaultWeights, Quiet: true}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := []string{}
+		for _, f := range fwd {
+			if f.Distance == 0 {
+				names = append(names, f.Func.Name)
+			}
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("search(%q) found %v at a distance of 0, want %v", tt.query, names, tt.want)
+		}
+	}
+}
+
+func TestMatchTypes(t *testing.T) {
+	dir := t.TempDir()
+	source := `package a
+
+import (
+	"io"
+	"os"
+)
+
+func Load(f *os.File) error { return nil }
+
+func Copy(r io.Reader) (int, error) { return 0, nil }
+
+func Name(s string) string { return s }
+
+func Create(name string) (*os.File, error) { return os.Create(name) }
+
+func Join(sep string, parts ...string) string { return sep }
+
+func First[T any](xs []T) T { return xs[0] }
+`
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module a\n\ngo 1.22\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	gt, err := loadGoTypes([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	funcs, err := getFuncs([]byte(source), file{Language: "golang", Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"( io.Reader ) -> ( error )", []string{"Load"}},
+		{"( io.Reader ) -> ( int, error )", []string{"Copy"}},
+		{"( io.ByteReader ) -> ( error )", []string{}},
+		{"( string ) -> ( string )", []string{"Name", "Join", "First"}},
+		{"( string, string, string ) -> ( string )", []string{"Join"}},
+		{"( _ ) ->", []string{"Load", "Copy", "Name", "Create", "Join", "First"}},
+		{"( []int ) -> ( int )", []string{"First"}},
+		// an io.Reader cannot be used where a *os.File is taken
+		{"( *os.File ) -> ( int, error )", []string{}},
+		// results are assignable to the types in the query
+		{"( string ) -> ( io.Writer, error )", []string{"Create"}},
+		{"( string ) -> ( *os.File, error )", []string{"Create"}},
+		{"( string ) -> ( io.ByteReader, error )", []string{}},
+	}
+
+	for _, tt := range tests {
+		fwd, err := search(funcs, tt.query, "types", false, searchOptions{Weights: defaultWeights, Quiet: true}, gt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := []string{}
+		for _, f := range fwd {
+			names = append(names, f.Func.Name)
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("search(%q) = %v, want %v", tt.query, names, tt.want)
+		}
+	}
+}
+
+func TestRankTypeParams(t *testing.T) {
+	funcs := []Func{
+		{Name: "Identity", Loc: []int{0, 0, 0}, Args: []string{"T"}, Rets: []string{"T"}, TypeParams: []string{"T"}},
+		{Name: "Zero", Loc: []int{1, 0, 0}, Args: []string{}, Rets: []string{"T"}, TypeParams: []string{"T"}},
+		{Name: "First", Loc: []int{2, 0, 0}, Args: []string{"[]T"}, Rets: []string{"T"}, TypeParams: []string{"T"}},
+		{Name: "Fields", Loc: []int{3, 0, 0}, Args: []string{"string"}, Rets: []string{"[]string"}},
+		{Name: "Sum", Loc: []int{4, 0, 0}, Args: []string{"[]int"}, Rets: []string{"int"}},
+		{Name: "Trim", Loc: []int{5, 0, 0}, Args: []string{"string"}, Rets: []string{"string"}},
+	}
+
+	tests := []struct {
+		query string
+		want  []string // names of the closest functions, closest first
+	}{
+		// a type parameter bound only by the outputs could be anything
+		{"-> ( []string )", []string{"Fields", "Identity", "Zero"}},
+		// binding a type parameter costs a little
+		{"( []int ) -> ( int )", []string{"Sum", "First"}},
+		{"( string ) -> ( string )", []string{"Trim", "Identity"}},
+	}
+
+	for _, tt := range tests {
+		fwd, err := search(funcs, tt.query, "default", false, searchOptions{Weights: defaultWeights, Quiet: true}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names := []string{}
+		for _, f := range fwd[:len(tt.want)] {
+			names = append(names, f.Func.Name)
+		}
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("search(%q) ranked %v first, want %v", tt.query, names, tt.want)
+		}
+		if fwd[0].Distance != 0 || fwd[1].Distance == 0 {
+			t.Errorf("search(%q) = %v with distances %v and %v, want only the first at 0", tt.query, names, fwd[0].Distance, fwd[1].Distance)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// docComment returns the comments right before fn (skipping decorators
+// and attributes) or, if there are none, the docstring at the start of
+// its body (like in Python), without the comment markers
+func docComment(fn, body *sitter.Node, sourceCode []byte) string {
+	comments := []string{}
+
+	n := fn
+	for {
+		next := n
+		for s := n.PrevNamedSibling(); s != nil; s = s.PrevNamedSibling() {
+			t := s.Type()
+			if strings.Contains(t, "decorator") || strings.Contains(t, "attribute") || strings.Contains(t, "annotation") {
+				next = s
+				continue
+			}
+			// comments have to be right above and not after some code
+			if !strings.Contains(t, "comment") || s.EndPoint().Row+1 < next.StartPoint().Row {
+				break
+			}
+			if p := s.PrevNamedSibling(); p != nil && !strings.Contains(p.Type(), "comment") && p.EndPoint().Row == s.StartPoint().Row {
+				break
+			}
+			comments = append([]string{s.Content(sourceCode)}, comments...)
+			next = s
+		}
+		if len(comments) > 0 {
+			break
+		}
+
+		// the comments could be before a declaration or export that
+		// wraps the function (eg: `export function` or `const f = () =>`)
+		p := n.Parent()
+		if p == nil || p.Parent() == nil || (p.StartPoint().Row != n.StartPoint().Row && !strings.Contains(p.Type(), "decorated")) {
+			break
+		}
+		n = p
+	}
+
+	if len(comments) == 0 && body != nil && body.NamedChildCount() > 0 {
+		first := body.NamedChild(0)
+		if first.Type() == "expression_statement" && first.NamedChildCount() > 0 {
+			first = first.NamedChild(0)
+		}
+		if first.Type() == "string" {
+			comments = append(comments, first.Content(sourceCode))
+		}
+	}
+
+	return cleanComment(strings.Join(comments, "\n"))
+}
+
+// commentMarkers are removed from the start of the lines of comments,
+// longest first
+var commentMarkers = []string{
+	"{- |", `"""`, `'''`, "/**", "/*!", "///", "//!", "-- |", "-- ^", "---",
+	";;;", "(**", "#'", "/*", "//", "(*", "{-", "--", ";;", "##", "%%", "*",
+	"#", ";", "%", `"`,
+}
+
+// cleanComment removes the comment markers and the blank lines around
+// the text of a comment
+func cleanComment(comment string) string {
+	lines := []string{}
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(line)
+		for _, s := range []string{"*/", "*)", "-}", `"""`, `'''`} {
+			line = strings.TrimSuffix(line, s)
+		}
+		line = strings.TrimSpace(line)
+		for _, m := range commentMarkers {
+			if strings.HasPrefix(line, m) {
+				line = line[len(m):]
+				break
+			}
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+// commentAbove is the doc of the parsers without a grammar for a
+// function starting on row of lines: the lines starting with one of
+// markers right above it or, if close is not empty, the block comment
+// from open to close ending right above it. Annotations (lines starting
+// with @) between them and the function are skipped.
+func commentAbove(lines []string, row int, markers []string, open, close string) string {
+	end := row
+	for end > 0 && strings.HasPrefix(strings.TrimSpace(lines[end-1]), "@") {
+		end--
+	}
+
+	if close != "" && end > 0 && strings.HasSuffix(strings.TrimSpace(lines[end-1]), close) {
+		for start := end - 1; start >= 0; start-- {
+			line := strings.TrimSpace(lines[start])
+			if start == end-1 {
+				line = strings.TrimSuffix(line, close)
+			}
+			if strings.Contains(line, open) {
+				comment := strings.TrimSpace(strings.Join(lines[start:end], "\n"))
+				return cleanComment(strings.TrimSuffix(comment, close))
+			}
+		}
+		return ""
+	}
+
+	start := end
+	for start > 0 && hasAnyPrefix(strings.TrimSpace(lines[start-1]), markers) {
+		start--
+	}
+	return cleanComment(strings.Join(lines[start:end], "\n"))
+}
+
+// hasAnyPrefix checks if s starts with one of prefixes
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// docSummary is the first line of the doc of f
+func docSummary(f Func) string {
+	summary, _, _ := strings.Cut(f.Doc, "\n")
+	return summary
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// embedBatchSize is the number of signatures embedded per request
+const embedBatchSize = 128
+
+// embedClient makes the requests to the embeddings endpoint, which can
+// take a while when a local model has to be loaded first
+var embedClient = &http.Client{Timeout: 2 * time.Minute}
+
+// embedder computes embeddings using an OpenAI compatible embeddings
+// endpoint (eg: http://localhost:11434/v1/embeddings for ollama)
+type embedder struct {
+	URL   string
+	Model string
+	Key   string // sent as a bearer token, if set
+}
+
+type embedRequest struct {
+	Model string   `json:"model,omitempty"`
+	Input []string `json:"input"`
+}
+
+type embedResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+// embed returns the embeddings of texts in the same order
+func (e embedder) embed(texts []string) ([][]float64, error) {
+	embeddings := [][]float64{}
+	for start := 0; start < len(texts); start += embedBatchSize {
+		end := min(start+embedBatchSize, len(texts))
+
+		body, err := json.Marshal(embedRequest{Model: e.Model, Input: texts[start:end]})
+		if err != nil {
+			return nil, err
+		}
+
+		req, err := http.NewRequest(http.MethodPost, e.URL, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if e.Key != "" {
+			req.Header.Set("Authorization", "Bearer "+e.Key)
+		}
+
+		resp, err := embedClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var r embedResponse
+		err = json.NewDecoder(resp.Body).Decode(&r)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("embedding request failed: %s", resp.Status)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(r.Data) != end-start {
+			return nil, fmt.Errorf("expected %d embeddings, got %d", end-start, len(r.Data))
+		}
+
+		batch := make([][]float64, end-start)
+		for _, d := range r.Data {
+			if d.Index < 0 || d.Index >= len(batch) {
+				return nil, fmt.Errorf("invalid embedding index %d", d.Index)
+			}
+			batch[d.Index] = d.Embedding
+		}
+		embeddings = append(embeddings, batch...)
+	}
+
+	return embeddings, nil
+}
+
+// embedText is what is embedded for f: its name, signature and doc
+func embedText(f Func) string {
+	text := f.FullName() + " " + f.LabelledSignature()
+	if f.Doc != "" {
+		text += "\n" + f.Doc
+	}
+	return text
+}
+
+// sortBySimilarity sorts funcs by the cosine similarity of the
+// embeddings of their signatures (and docs) and the closest of the
+// queries, which can be any text (eg: "read file into struct"). The
+// distance is 1 - similarity. The embeddings of the functions are kept
+// in cache (if it is not nil) with the functions of their files, so
+// that only the ones of the files that changed are requested again.
+func sortBySimilarity(funcs []Func, queries []string, e embedder, cache *funcCache) ([]FuncWithDistance, error) {
+	cached := map[string]map[string][]float64{}
+	if cache != nil {
+		for _, f := range funcs {
+			if _, ok := cached[f.Path]; !ok {
+				if cached[f.Path] = cache.getEmbeddings(f.Path, e); cached[f.Path] == nil {
+					cached[f.Path] = map[string][]float64{}
+				}
+			}
+		}
+	}
+
+	texts := append([]string{}, queries...)
+	vectors := make([][]float64, len(funcs))
+	missing := []int{} // the functions whose embeddings are not cached
+	for i, f := range funcs {
+		text := embedText(f)
+		if v, ok := cached[f.Path][text]; ok {
+			vectors[i] = v
+			continue
+		}
+		missing = append(missing, i)
+		texts = append(texts, text)
+	}
+	debugf(1, "embedding %d of %d functions (the others are cached)", len(missing), len(funcs))
+
+	embeddings, err := e.embed(texts)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := map[string]bool{}
+	for j, i := range missing {
+		vectors[i] = embeddings[len(queries)+j]
+		if v := cached[funcs[i].Path]; v != nil {
+			v[texts[len(queries)+j]] = vectors[i]
+			changed[funcs[i].Path] = true
+		}
+	}
+	for path := range changed {
+		if err := cache.putEmbeddings(path, e, cached[path]); err != nil {
+			debugf(1, "not caching the embeddings of %s: %s", path, err)
+		}
+	}
+
+	fwd := []FuncWithDistance{}
+	for i, f := range funcs {
+		best := FuncWithDistance{Func: f, Distance: math.Inf(1)}
+		for q, query := range queries {
+			d := 1 - cosineSimilarity(embeddings[q], vectors[i])
+			if d < best.Distance {
+				best.Distance = d
+				if len(queries) > 1 {
+					best.Query = query
+				}
+			}
+		}
+		fwd = append(fwd, best)
+	}
+
+	sort.SliceStable(fwd, func(i, j int) bool { return fwd[i].Distance < fwd[j].Distance })
+	return fwd, nil
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := 0; i < len(a) && i < len(b); i++ {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// erlangSpec matches the start of a `-spec` (or `-callback`)
+	// attribute up to the opening paren of the first clause, eg:
+	// `-spec lists:map(`
+	erlangSpec = regexp.MustCompile(`^-\s*(?:spec|callback)\s*(\()?\s*(?:[a-z][\w@]*\s*:\s*)?([a-z][\w@]*|'[^']*')\s*\(`)
+
+	// erlangClause matches the start of a function clause, eg: `foo(`
+	erlangClause = regexp.MustCompile(`^([a-z][\w@]*|'[^']*')\s*\(`)
+
+	// erlangAnnotation matches the name in annotated types like `Key :: atom()`
+	erlangAnnotation = regexp.MustCompile(`\b[A-Z_][\w@]*\s*::\s*`)
+
+	// erlangVariable matches type variables, eg: `Key`
+	erlangVariable = regexp.MustCompile(`\b[A-Z_][\w@]*\b`)
+)
+
+// parseErlang finds functions in Erlang source. There is no tree-sitter
+// grammar for Erlang available to us, but the source can be split into
+// forms (which end with a `.`). Functions with a `-spec` use the types
+// from it (one entry per clause of the spec), others use the patterns
+// of their first clause as inputs and `_` as the output so that they
+// can still be found by name and arity.
+func parseErlang(sourceCode []byte, path string) []Func {
+	funcs := []Func{}
+	src := blankCommentsAndStrings(string(sourceCode), erlangSyntax)
+	lines := strings.Split(string(sourceCode), "\n")
+
+	type spec struct {
+		funcs []Func
+		used  bool
+	}
+	specs := map[string]*spec{}
+	order := []string{}
+
+	defined := map[string]bool{}
+	for _, form := range erlangForms(src) {
+		text := src[form[0]:form[1]]
+		loc := span(sourceCode, form[0], form[1])
+
+		if m := erlangSpec.FindStringSubmatchIndex(text); m != nil {
+			name := strings.Trim(text[m[4]:m[5]], "'")
+			doc := commentAbove(lines, loc[0], []string{"%"}, "", "")
+			body := strings.TrimSpace(text[m[1]-1:])
+			if m[2] != -1 {
+				body = strings.TrimSuffix(body, ")") // `-spec(foo() -> ok).`
+			}
+
+			for _, clause := range erlangSplit(body, ";") {
+				args, rets, ok := erlangSpecClause(clause)
+				if !ok {
+					continue
+				}
+				key := erlangKey(name, len(args))
+				if specs[key] == nil {
+					specs[key] = &spec{}
+					order = append(order, key)
+				}
+				specs[key].funcs = append(specs[key].funcs, Func{
+					Path: path,
+					Loc:  loc,
+					Name: name,
+					Args: args,
+					Rets: rets,
+					Doc:  doc,
+				})
+			}
+			continue
+		}
+
+		m := erlangClause.FindStringSubmatchIndex(text)
+		if m == nil {
+			continue
+		}
+
+		name := strings.Trim(text[m[2]:m[3]], "'")
+		body := bodyRows(sourceCode, form[0], form[1]+1) // with the `.`
+		end := matchingBracket(text, m[1]-1)
+		if end == -1 {
+			continue
+		}
+
+		args := []string{}
+		for _, a := range erlangSplit(text[m[1]:end], ",") {
+			if a = strings.Join(strings.Fields(a), " "); a != "" {
+				args = append(args, a)
+			}
+		}
+
+		key := erlangKey(name, len(args))
+		if defined[key] {
+			continue
+		}
+		defined[key] = true
+
+		if s := specs[key]; s != nil {
+			for _, f := range s.funcs {
+				f.Loc, f.BodyLoc = loc, body
+				// the doc can be above the spec or the function
+				if f.Doc == "" {
+					f.Doc = commentAbove(lines, loc[0], []string{"%"}, "", "")
+				}
+				funcs = append(funcs, f)
+			}
+			s.used = true
+			continue
+		}
+
+		funcs = append(funcs, Func{
+			Path:    path,
+			Loc:     loc,
+			Name:    name,
+			Args:    args,
+			Rets:    []string{"_"},
+			Doc:     commentAbove(lines, loc[0], []string{"%"}, "", ""),
+			BodyLoc: body,
+		})
+	}
+
+	// specs without a definition (eg: callbacks of a behaviour)
+	for _, key := range order {
+		if !specs[key].used {
+			funcs = append(funcs, specs[key].funcs...)
+		}
+	}
+
+	return funcs
+}
+
+// erlangSpecClause splits a single clause of a spec like
+// `(Key, [T]) -> T when Key :: atom()` into inputs and outputs,
+// replacing the variables constrained in the `when` with their types.
+func erlangSpecClause(clause string) ([]string, []string, bool) {
+	clause = strings.TrimSpace(clause)
+	if !strings.HasPrefix(clause, "(") {
+		return nil, nil, false
+	}
+	end := matchingBracket(clause, 0)
+	if end == -1 {
+		return nil, nil, false
+	}
+
+	rest := strings.TrimSpace(clause[end+1:])
+	if !strings.HasPrefix(rest, "->") {
+		return nil, nil, false
+	}
+	rest = strings.Join(strings.Fields(rest[2:]), " ")
+
+	constraints := map[string]string{}
+	if parts := erlangSplit(rest, " when "); len(parts) > 1 {
+		rest = parts[0]
+		for _, c := range erlangSplit(parts[1], ",") {
+			if kv := strings.SplitN(c, "::", 2); len(kv) == 2 {
+				constraints[strings.TrimSpace(kv[0])] = erlangAnnotation.ReplaceAllString(strings.TrimSpace(kv[1]), "")
+			}
+		}
+	}
+
+	normalize := func(t string) string {
+		t = erlangAnnotation.ReplaceAllString(strings.Join(strings.Fields(t), " "), "")
+		return erlangVariable.ReplaceAllStringFunc(t, func(v string) string {
+			if c, ok := constraints[v]; ok {
+				return c
+			}
+			return v
+		})
+	}
+
+	args := []string{}
+	for _, a := range erlangSplit(clause[1:end], ",") {
+		if strings.TrimSpace(a) != "" {
+			args = append(args, normalize(a))
+		}
+	}
+	return args, []string{normalize(rest)}, true
+}
+
+// erlangForms returns the start and end offsets of the top level forms
+// in src, each of which ends with a `.` followed by whitespace
+func erlangForms(src string) [][]int {
+	forms := [][]int{}
+	start := 0
+	for i := 0; i < len(src); i++ {
+		if src[i] != '.' || (i+1 < len(src) && !strings.ContainsRune(" \t\r\n", rune(src[i+1]))) {
+			continue
+		}
+
+		for start < i && strings.ContainsRune(" \t\r\n", rune(src[start])) {
+			start++
+		}
+		forms = append(forms, []int{start, i})
+		start = i + 1
+	}
+	return forms
+}
+
+// erlangSplit is splitTopLevel, but also treating binaries (`<<A:8, B/binary>>`)
+// as brackets
+func erlangSplit(s, sep string) []string {
+	masked := strings.NewReplacer("<<", "((", ">>", "))").Replace(s)
+
+	parts := []string{}
+	offset := 0
+	for _, p := range splitTopLevel(masked, sep) {
+		parts = append(parts, s[offset:offset+len(p)])
+		offset += len(p) + len(sep)
+	}
+	return parts
+}
+
+// erlangKey identifies a function by name and arity, eg: `foo/2`
+func erlangKey(name string, arity int) string {
+	return name + "/" + strconv.Itoa(arity)
+}
+
+// erlangSyntax are the comments and literals of Erlang: strings,
+// character literals like $( and quoted atoms, which keep their content
+var erlangSyntax = lexicalSyntax{
+	lineComments: []string{"%"},
+	shebang:      true, // escripts
+	quotes: []quoteSyntax{
+		{open: `"`, close: `"`, escape: true},
+		{open: "'", close: "'", escape: true, keep: true},
+	},
+	keepQuotes: true,
+	literal: func(src string) int {
+		if src[0] != '$' {
+			return 0
+		}
+		n := 1
+		if n < len(src) && src[n] == '\\' {
+			n++
+		}
+		if n < len(src) && src[n] != '\n' {
+			n++
+		}
+		return n
+	},
+}
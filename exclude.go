@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// splitExclusions removes the types starting with `!` from a query like
+// `( !context.Context ) -> ( error )` and returns them separately, as
+// the arguments and results that functions must not have. A side with
+// only exclusions matches any types. Types that do start with `!` (Zig
+// error unions) can be written as `\!void`.
+func splitExclusions(uinput string) (string, []string, []string) {
+	if !strings.Contains(uinput, "!") {
+		return uinput, nil, nil
+	}
+
+	inputs, outputs, err := getInputsAndOutput(uinput)
+	if err != nil {
+		return uinput, nil, nil
+	}
+
+	split := func(types []string) ([]string, []string) {
+		if types == nil {
+			return nil, nil
+		}
+
+		kept, excluded := []string{}, []string{}
+		for _, t := range types {
+			switch {
+			case strings.HasPrefix(t, `\!`):
+				kept = append(kept, t[1:])
+			case strings.HasPrefix(t, "!"):
+				if t = strings.TrimSpace(t[1:]); t != "" {
+					excluded = append(excluded, t)
+				}
+			default:
+				kept = append(kept, t)
+			}
+		}
+		if len(kept) == 0 && len(excluded) > 0 {
+			kept = nil
+		}
+		return kept, excluded
+	}
+
+	inputs, notArgs := split(inputs)
+	outputs, notRets := split(outputs)
+
+	side := func(types []string) string {
+		if types == nil {
+			return ""
+		}
+		return "( " + strings.Join(types, ", ") + " )"
+	}
+	return strings.TrimSpace(side(inputs) + " -> " + side(outputs)), notArgs, notRets
+}
+
+// parseExclusions parses the value of -not-arg and -not-ret, a comma
+// separated list of types
+func parseExclusions(s string) []string {
+	types := []string{}
+	for _, t := range splitTopLevel(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// checkExclusions rejects wildcards in the excluded types, as they
+// would leave out every function
+func checkExclusions(notArgs, notRets []string) error {
+	for _, t := range append(append([]string{}, notArgs...), notRets...) {
+		if isWildcard(t) {
+			return fmt.Errorf("cannot exclude the wildcard '%s'", t)
+		}
+	}
+	return nil
+}
+
+// filterExcluded drops the functions that take any of the types in
+// notArgs or return any of the ones in notRets. The types have to match
+// exactly, excluding `int` keeps the functions that take a `Point`.
+func filterExcluded(funcs []Func, notArgs, notRets []string, opts searchOptions) []Func {
+	if len(notArgs) == 0 && len(notRets) == 0 {
+		return funcs
+	}
+
+	prepare := func(types []string) []string {
+		prepared := []string{}
+		for _, t := range types {
+			prepared = append(prepared, opts.prepareType(t))
+		}
+		return prepared
+	}
+	notArgs, notRets = prepare(notArgs), prepare(notRets)
+
+	filtered := []Func{}
+	for _, f := range funcs {
+		nf := opts.prepare(f, nil, nil, false)
+		args := append(nf.LabelledArgs(), nf.Args...)
+		if !containsAny(args, notArgs) && !containsAny(nf.Rets, notRets) {
+			filtered = append(filtered, f)
+		}
+	}
+	return filtered
+}
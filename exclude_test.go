@@ -0,0 +1,48 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchExclusions(t *testing.T) {
+	funcs := []Func{
+		{Name: "Draw", Loc: []int{0, 0, 0}, Args: []string{"Point"}, Rets: []string{"error"}},
+		{Name: "Add", Loc: []int{1, 0, 0}, Args: []string{"int"}, Rets: []string{"error"}},
+		{Name: "Handle", Loc: []int{2, 0, 0}, Args: []string{"string"}, Rets: []string{"*MyErrorHandler"}},
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		opts   searchOptions
+		want   []string
+		hasErr bool
+	}{
+		{"inline argument", "( !int ) -> ( error )", searchOptions{}, []string{"Draw"}, false},
+		{"not-arg flag", "-> ( error )", searchOptions{NotArgs: []string{"int"}}, []string{"Draw"}, false},
+		{"inline result", "( string ) -> ( !error )", searchOptions{}, []string{"Handle"}, false},
+		{"not-ret flag", "( string ) ->", searchOptions{NotRets: []string{"error"}}, []string{"Handle"}, false},
+		{"ignore case", "( !INT ) ->", searchOptions{IgnoreCase: true}, []string{"Draw", "Handle"}, false},
+		{"inline wildcard", "( !_ ) -> ( error )", searchOptions{}, nil, true},
+		{"not-arg wildcard", "-> ( error )", searchOptions{NotArgs: []string{"?"}}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Quiet = true
+			fwd, err := search(funcs, tt.query, "includes", false, tt.opts, nil)
+			if (err != nil) != tt.hasErr {
+				t.Fatalf("search(%q) error = %v, want error %v", tt.query, err, tt.hasErr)
+			}
+
+			var names []string
+			for _, f := range fwd {
+				names = append(names, f.Func.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("search(%q) = %v, want %v", tt.query, names, tt.want)
+			}
+		})
+	}
+}
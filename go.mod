@@ -1,8 +1,17 @@
 module github.com/meain/glee
 
-go 1.20
+go 1.22.0
 
 require (
-	github.com/agnivade/levenshtein v1.1.1
-	github.com/smacker/go-tree-sitter v0.0.0-20230720070738-0d0a9f78d8f8
+	github.com/BurntSushi/toml v1.6.0
+	github.com/fsnotify/fsnotify v1.8.0
+	github.com/smacker/go-tree-sitter v0.0.0-20240827094217-dd81d9e9be82
+	github.com/tree-sitter-grammars/tree-sitter-zig v1.1.2
+)
+
+require (
+	golang.org/x/mod v0.23.0 // indirect
+	golang.org/x/sync v0.11.0 // indirect
+	golang.org/x/sys v0.30.0 // indirect
+	golang.org/x/tools v0.30.0
 )
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// goTypes has the type information of the Go packages under a
+// directory, used to match functions by assignability instead of by
+// the names of their types
+type goTypes struct {
+	sigs   map[string]*types.Signature // by "path:row:col" of the declaration
+	local  []*types.Package            // packages under the directory
+	byName map[string][]*types.Package // all known packages by name
+	seen   map[*types.Package]bool
+}
+
+// loadGoTypes type checks the Go packages (including tests) under the
+// roots
+func loadGoTypes(roots []string) (*goTypes, error) {
+	pkgs := []*packages.Package{}
+	for _, root := range roots {
+		cfg := &packages.Config{
+			Mode:  packages.NeedName | packages.NeedImports | packages.NeedDeps | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo,
+			Dir:   root,
+			Tests: true,
+		}
+
+		loaded, err := packages.Load(cfg, "./...")
+		if err != nil {
+			return nil, err
+		}
+		pkgs = append(pkgs, loaded...)
+	}
+
+	g := &goTypes{
+		sigs:   map[string]*types.Signature{},
+		byName: map[string][]*types.Package{},
+		seen:   map[*types.Package]bool{},
+	}
+
+	for _, pkg := range pkgs {
+		if pkg.Types == nil || pkg.TypesInfo == nil {
+			continue
+		}
+
+		g.local = append(g.local, pkg.Types)
+		g.addPackage(pkg.Types)
+
+		for _, file := range pkg.Syntax {
+			for _, decl := range file.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+
+				fn, ok := pkg.TypesInfo.Defs[fd.Name].(*types.Func)
+				if !ok {
+					continue
+				}
+
+				pos := pkg.Fset.Position(fd.Pos())
+				g.sigs[goTypesKey(pos.Filename, pos.Line-1, pos.Column-1)] = fn.Type().(*types.Signature)
+			}
+		}
+	}
+
+	return g, nil
+}
+
+// addPackage makes pkg (and everything it imports) available to query types
+func (g *goTypes) addPackage(pkg *types.Package) {
+	if g.seen[pkg] {
+		return
+	}
+	g.seen[pkg] = true
+
+	g.byName[pkg.Name()] = append(g.byName[pkg.Name()], pkg)
+	for _, imp := range pkg.Imports() {
+		g.addPackage(imp)
+	}
+}
+
+func goTypesKey(path string, row, col int) string {
+	abs, err := filepath.Abs(path)
+	if err == nil {
+		path = abs
+	}
+	return fmt.Sprintf("%s:%d:%d", path, row, col)
+}
+
+// filterTypes keeps the Go functions whose parameters and results are
+// assignable to the types at the same position in the query, eg: a
+// function taking a `*os.File` for an `io.Reader`. Other functions
+// (and generic functions) are filtered like with filterIncludes.
+func (g *goTypes) filterTypes(funcs []Func, inputs, outputs []string, opts searchOptions) []Func {
+	// partial queries (nil inputs or outputs) match any inputs or outputs
+	anyInputs, anyOutputs := inputs == nil, outputs == nil
+	inputs, outputs = nonEmpty(inputs), nonEmpty(outputs)
+	names := append(append([]string{}, inputs...), outputs...)
+	query := []types.Type{}
+	for _, t := range names {
+		query = append(query, g.lookup(t))
+	}
+
+	filtered := []Func{}
+	for _, f := range funcs {
+		// generic functions are unified by the names of their types
+		sig, ok := g.sigs[goTypesKey(f.Path, f.Loc[0], f.Loc[1])]
+		if !ok || sig.TypeParams().Len() > 0 {
+			filtered = append(filtered, filterIncludes([]Func{f}, inputs, outputs, opts)...)
+			continue
+		}
+
+		// the elements of variadic parameters are matched against
+		// the rest of the inputs, unless the query has `...T` there
+		params := sig.Params().Len()
+		spread := sig.Variadic() && !(len(inputs) == params && strings.HasPrefix(inputs[params-1], "..."))
+		arity := params == len(inputs) || (spread && len(inputs) >= params-1)
+		if (!anyInputs && !arity) || (!anyOutputs && sig.Results().Len() != len(outputs)) {
+			continue
+		}
+
+		match := true
+		for i := 0; i < len(query) && match; i++ {
+			var t types.Type
+			switch {
+			case i < len(inputs) && spread && i >= params-1:
+				t = sig.Params().At(params - 1).Type().(*types.Slice).Elem()
+			case i < len(inputs):
+				t = sig.Params().At(i).Type()
+			default:
+				t = sig.Results().At(i - len(inputs)).Type()
+			}
+			match = g.assignable(t, query[i], names[i])
+		}
+
+		if match {
+			filtered = append(filtered, f)
+		}
+	}
+
+	return filtered
+}
+
+// assignable checks if a value of type t can be used as the query type
+// q (or if it has the same name as the query type when that could not
+// be resolved)
+func (g *goTypes) assignable(t, q types.Type, name string) bool {
+	if isWildcard(name) {
+		return true
+	}
+	if q == nil {
+		return types.TypeString(t, func(p *types.Package) string { return p.Name() }) == name
+	}
+	return types.AssignableTo(t, q)
+}
+
+// lookup resolves a type in a query like `*bytes.Buffer` or
+// `map[string]io.Reader`, returning nil if it cannot be resolved
+func (g *goTypes) lookup(t string) types.Type {
+	if isWildcard(t) {
+		return nil
+	}
+
+	// variadic parameters are slices
+	if elem, ok := strings.CutPrefix(t, "..."); ok {
+		if e := g.lookup(elem); e != nil {
+			return types.NewSlice(e)
+		}
+		return nil
+	}
+
+	expr, err := parser.ParseExpr(t)
+	if err != nil {
+		return nil
+	}
+	return g.resolve(expr)
+}
+
+func (g *goTypes) resolve(expr ast.Expr) types.Type {
+	typeName := func(obj types.Object) types.Type {
+		if tn, ok := obj.(*types.TypeName); ok {
+			return tn.Type()
+		}
+		return nil
+	}
+
+	switch e := expr.(type) {
+	case *ast.Ident:
+		if obj := types.Universe.Lookup(e.Name); obj != nil {
+			return typeName(obj)
+		}
+		for _, pkg := range g.local {
+			if obj := pkg.Scope().Lookup(e.Name); obj != nil {
+				return typeName(obj)
+			}
+		}
+	case *ast.SelectorExpr:
+		pkg, ok := e.X.(*ast.Ident)
+		if !ok {
+			return nil
+		}
+		for _, p := range g.packages(pkg.Name) {
+			if obj := p.Scope().Lookup(e.Sel.Name); obj != nil {
+				return typeName(obj)
+			}
+		}
+	case *ast.StarExpr:
+		if elem := g.resolve(e.X); elem != nil {
+			return types.NewPointer(elem)
+		}
+	case *ast.ArrayType:
+		if elem := g.resolve(e.Elt); elem != nil && e.Len == nil {
+			return types.NewSlice(elem)
+		}
+	case *ast.MapType:
+		key, value := g.resolve(e.Key), g.resolve(e.Value)
+		if key != nil && value != nil {
+			return types.NewMap(key, value)
+		}
+	case *ast.ChanType:
+		if elem := g.resolve(e.Value); elem != nil {
+			return types.NewChan(types.SendRecv, elem)
+		}
+	case *ast.InterfaceType:
+		if e.Methods == nil || len(e.Methods.List) == 0 {
+			return types.NewInterfaceType(nil, nil).Complete()
+		}
+	}
+
+	return nil
+}
+
+// packages returns the packages called name, loading it from the
+// standard library (eg: io) if no package under the directory uses it
+func (g *goTypes) packages(name string) []*types.Package {
+	if pkgs, ok := g.byName[name]; ok {
+		return pkgs
+	}
+
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.NeedName | packages.NeedImports | packages.NeedDeps | packages.NeedTypes}, name)
+	if err == nil {
+		for _, pkg := range pkgs {
+			if pkg.Types != nil && pkg.Name == name {
+				g.addPackage(pkg.Types)
+			}
+		}
+	}
+
+	if _, ok := g.byName[name]; !ok {
+		g.byName[name] = nil // do not try again
+	}
+	return g.byName[name]
+}
+
+// nonEmpty drops empty types (from queries like `() -> ( error )`)
+func nonEmpty(ts []string) []string {
+	out := []string{}
+	for _, t := range ts {
+		if t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// graphqlType matches the start of an object or interface type
+	// up to the opening brace of its fields, eg: `extend type Query {`
+	graphqlType = regexp.MustCompile(`\b(?:extend\s+)?(type|interface)\s+([_A-Za-z]\w*)[^{}]*\{`)
+
+	// graphqlSchema matches the schema definition, eg: `schema {`
+	graphqlSchema = regexp.MustCompile(`\b(?:extend\s+)?schema\b[^{}]*\{([^}]*)\}`)
+
+	// graphqlOperation matches an operation type in a schema definition, eg: `query: RootQuery`
+	graphqlOperation = regexp.MustCompile(`\b(?:query|mutation|subscription)\s*:\s*([_A-Za-z]\w*)`)
+
+	graphqlName      = regexp.MustCompile(`^[_A-Za-z]\w*`)
+	graphqlTypeRef   = regexp.MustCompile(`^(?:\[\s*)*[_A-Za-z]\w*\s*!?(?:\s*\]\s*!?)*`)
+	graphqlDirective = regexp.MustCompile(`^@[_A-Za-z]\w*`)
+)
+
+// parseGraphQL finds the fields of the root operation types (Query,
+// Mutation and Subscription) in GraphQL schemas, along with the fields
+// of other types that take arguments. There is no tree-sitter grammar
+// for GraphQL available to us, but SDL is simple enough to scan. Fields
+// are shown as `Type.field` and their arguments are labelled.
+func parseGraphQL(sourceCode []byte, path string) []Func {
+	funcs := []Func{}
+	src := blankCommentsAndStrings(string(sourceCode), graphqlSyntax)
+	lines := strings.Split(string(sourceCode), "\n")
+
+	operations := map[string]bool{"Query": true, "Mutation": true, "Subscription": true}
+	if m := graphqlSchema.FindStringSubmatch(src); m != nil {
+		operations = map[string]bool{}
+		for _, op := range graphqlOperation.FindAllStringSubmatch(m[1], -1) {
+			operations[op[1]] = true
+		}
+	}
+
+	for _, m := range graphqlType.FindAllStringSubmatchIndex(src, -1) {
+		typeName := src[m[4]:m[5]]
+		open := m[1] - 1
+
+		end := matchingBracket(src, open)
+		if end == -1 {
+			continue
+		}
+
+		fields := graphqlFields(src, open+1, end)
+		for _, field := range fields {
+			if len(field.Args) == 0 && !operations[typeName] {
+				continue
+			}
+
+			// fields have no body, their definition is shown instead
+			field.Path = path
+			field.BodyLoc = bodyRows(sourceCode, field.Loc[0], field.Loc[1])
+			field.Loc = position(sourceCode, field.Loc[0])
+			field.Receiver = typeName
+			// descriptions are strings, block or not
+			if field.Doc = commentAbove(lines, field.Loc[0], []string{"#"}, `"""`, `"""`); field.Doc == "" {
+				field.Doc = commentAbove(lines, field.Loc[0], nil, `"`, `"`)
+			}
+			funcs = append(funcs, field)
+		}
+	}
+
+	return funcs
+}
+
+// graphqlFields scans the field (or argument) definitions between
+// start and end in src. The Loc of the returned Funcs is the offset of
+// the field and of its end.
+func graphqlFields(src string, start, end int) []Func {
+	fields := []Func{}
+
+	i := start
+	skip := func() {
+		for i < end && strings.ContainsRune(" \t\r\n,", rune(src[i])) {
+			i++
+		}
+	}
+
+	for skip(); i < end; skip() {
+		name := graphqlName.FindString(src[i:end])
+		if name == "" {
+			i++
+			continue
+		}
+
+		f := Func{
+			Loc:    []int{i},
+			Name:   name,
+			Args:   []string{},
+			Labels: []string{},
+		}
+		i += len(name)
+
+		skip()
+		if i < end && src[i] == '(' {
+			close := matchingBracket(src, i)
+			if close == -1 || close > end {
+				break
+			}
+			for _, a := range graphqlFields(src, i+1, close) {
+				f.Args = append(f.Args, a.Rets...)
+				f.Labels = append(f.Labels, a.Name)
+			}
+			i = close + 1
+			skip()
+		}
+
+		if i >= end || src[i] != ':' {
+			continue
+		}
+		i++
+		skip()
+
+		t := graphqlTypeRef.FindString(src[i:end])
+		i += len(t)
+		f.Rets = []string{strings.Join(strings.Fields(t), "")}
+
+		// default values (of arguments) and directives
+	values:
+		for skip(); i < end; skip() {
+			switch {
+			case src[i] == '=':
+				i++
+				skip()
+				i = graphqlValueEnd(src, i, end)
+			case graphqlDirective.MatchString(src[i:end]):
+				i += len(graphqlDirective.FindString(src[i:end]))
+				if i < end && src[i] == '(' {
+					if close := matchingBracket(src, i); close != -1 {
+						i = close + 1
+					}
+				}
+			default:
+				break values
+			}
+		}
+
+		// without the blanks (and comments) skipped after it
+		last := i
+		for last > f.Loc[0] && strings.ContainsRune(" \t\r\n,", rune(src[last-1])) {
+			last--
+		}
+		f.Loc = append(f.Loc, last)
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+// graphqlValueEnd returns the offset right after the value starting at i
+func graphqlValueEnd(src string, i, end int) int {
+	depth := 0
+	for ; i < end; i++ {
+		switch src[i] {
+		case '[', '{', '(':
+			depth++
+		case ']', '}', ')':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		case ' ', '\t', '\r', '\n', ',':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return i
+}
+
+// graphqlSyntax are the comments and strings (like descriptions) of
+// GraphQL
+var graphqlSyntax = lexicalSyntax{
+	lineComments: []string{"#"},
+	quotes: []quoteSyntax{
+		{open: `"""`, close: `"""`, escape: true},
+		{open: `"`, close: `"`, escape: true},
+	},
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// groupings are the ways results can be grouped with -group-by
+var groupings = map[string]func(f Func) string{
+	"file":     func(f Func) string { return f.Path },
+	"package":  func(f Func) string { return filepath.Dir(f.Path) },
+	"language": func(f Func) string { return languageTitle(f.Language) },
+}
+
+// checkOrdering checks the values of -group-by and -sort
+func checkOrdering(groupBy, sortBy string) error {
+	if _, ok := groupings[groupBy]; groupBy != "" && !ok {
+		return fmt.Errorf("invalid grouping '%s' (options: %s)", groupBy, strings.Join(sortedKeys(groupings), ", "))
+	}
+	if _, ok := sortings[sortBy]; !ok {
+		return fmt.Errorf("invalid sort order '%s' (options: %s)", sortBy, strings.Join(sortedKeys(sortings), ", "))
+	}
+	return nil
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortings are the orders results can be shown in with -sort, after
+// picking the closest ones
+var sortings = map[string]func(a, b Func) bool{
+	"score": nil,
+	"path":  byLocation,
+	"name": func(a, b Func) bool {
+		if a.FullName() != b.FullName() {
+			return a.FullName() < b.FullName()
+		}
+		return byLocation(a, b)
+	},
+}
+
+// sortResults orders the results by path or name, keeping them ordered
+// by score otherwise
+func sortResults(fwd []FuncWithDistance, by string) []FuncWithDistance {
+	less := sortings[by]
+	if less == nil {
+		return fwd
+	}
+
+	sorted := append([]FuncWithDistance{}, fwd...)
+	sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i].Func, sorted[j].Func) })
+	return sorted
+}
+
+// groupResults puts the results in the same group (by file, package or
+// language) together, keeping the order within them. Groups are
+// ordered by their closest result.
+func groupResults(fwd []FuncWithDistance, by string) []FuncWithDistance {
+	group, ok := groupings[by]
+	if !ok {
+		return fwd
+	}
+
+	order := map[string]int{}
+	for _, f := range fwd {
+		if _, ok := order[group(f.Func)]; !ok {
+			order[group(f.Func)] = len(order)
+		}
+	}
+
+	grouped := append([]FuncWithDistance{}, fwd...)
+	sort.SliceStable(grouped, func(i, j int) bool {
+		return order[group(grouped[i].Func)] < order[group(grouped[j].Func)]
+	})
+	return grouped
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// haskellSignature matches the start of a type signature, eg:
+// `foo, bar :: Int -> Int` or `(<+>) :: a -> a -> a`
+var haskellSignature = regexp.MustCompile(`^(\s*)((?:[a-z_][\w']*|\([^\s()]+\))(?:\s*,\s*(?:[a-z_][\w']*|\([^\s()]+\)))*)\s*::(.*)$`)
+
+// haskellTypeVariable matches the (lowercase) type variables in a type
+var haskellTypeVariable = regexp.MustCompile(`\b[a-z_]\w*\b`)
+
+// parseHaskell finds type signatures in Haskell source. There is no
+// tree-sitter grammar for Haskell available to us, but signatures are
+// easy enough to find line by line: a signature starts with `name ::`
+// and continues on all the following lines that are indented more.
+func parseHaskell(sourceCode []byte, path string) []Func {
+	funcs := []Func{}
+
+	lines := strings.Split(string(sourceCode), "\n")
+	offsets := []int{}
+	offset := 0
+	for _, l := range lines {
+		offsets = append(offsets, offset)
+		offset += len(l) + 1
+	}
+
+	for i := 0; i < len(lines); i++ {
+		row := i
+		line := stripHaskellComment(lines[i])
+
+		// the `::` can also start the next line
+		if i+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i+1]), "::") {
+			line += " " + strings.TrimSpace(stripHaskellComment(lines[i+1]))
+			i++
+		}
+
+		m := haskellSignature.FindStringSubmatch(line)
+		if m == nil {
+			i = row
+			continue
+		}
+
+		indent := len(m[1])
+		sig := m[3]
+		for i+1 < len(lines) {
+			next := stripHaskellComment(lines[i+1])
+			if strings.TrimSpace(next) != "" && len(next)-len(strings.TrimLeft(next, " \t")) <= indent {
+				break
+			}
+			sig += " " + next
+			i++
+		}
+
+		args, rets := splitHaskellType(sig)
+		vars := haskellTypeVariables(append(append([]string{}, args...), rets...))
+		names := strings.Split(m[2], ",")
+		for n := range names {
+			names[n] = strings.TrimSpace(names[n])
+		}
+		for _, name := range names {
+			funcs = append(funcs, Func{
+				Path:       path,
+				Loc:        []int{row, indent, offsets[row] + indent, i, len(lines[i])},
+				Name:       name,
+				Args:       args,
+				Rets:       rets,
+				TypeParams: vars,
+				Doc:        commentAbove(lines, row, []string{"--"}, "{-", "-}"),
+				BodyLoc:    haskellBody(lines, i+1, indent, names, name),
+			})
+		}
+	}
+
+	return funcs
+}
+
+// haskellBody returns the first and last rows of the equations of name
+// (one of the names of a signature indented by indent) in the lines
+// starting at from, with the lines indented more that belong to them.
+// They end on the next line that is not indented more and does not
+// define one of the names (eg: the next signature).
+func haskellBody(lines []string, from, indent int, names []string, name string) []int {
+	var body []int
+	owner := ""
+	for j := from; j < len(lines); j++ {
+		line := stripHaskellComment(lines[j])
+		text := strings.TrimLeft(line, " \t")
+		if text == "" {
+			continue
+		}
+		if len(line)-len(text) <= indent {
+			if owner = haskellDefines(text, names); owner == "" {
+				break
+			}
+		}
+		if owner == name {
+			if body == nil {
+				body = []int{j, j}
+			}
+			body[1] = j
+		}
+	}
+	return body
+}
+
+// haskellDefines returns which of names line is an equation of, eg:
+// `go 0 = 1` or `x <+> y = ...` for `(<+>)`
+func haskellDefines(line string, names []string) string {
+	for _, name := range names {
+		if op := strings.TrimSuffix(strings.TrimPrefix(name, "("), ")"); op != name {
+			if strings.HasPrefix(line, name) || strings.Contains(line, " "+op+" ") {
+				return name
+			}
+			continue
+		}
+		if rest, ok := strings.CutPrefix(line, name); ok && (rest == "" || !isHaskellIdent(rest[0])) {
+			return name
+		}
+	}
+	return ""
+}
+
+func isHaskellIdent(c byte) bool {
+	return c == '_' || c == '\'' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// splitHaskellType normalizes a curried type like `Ord a => a -> [a] -> Bool`
+// into inputs (`a`, `[a]`) and outputs (`Bool`).
+func splitHaskellType(sig string) ([]string, []string) {
+	sig = strings.TrimSpace(sig)
+	if strings.HasPrefix(sig, "forall ") {
+		if dot := strings.Index(sig, "."); dot != -1 {
+			sig = sig[dot+1:]
+		}
+	}
+
+	// drop the constraints
+	parts := splitTopLevel(sig, "=>")
+	parts = splitTopLevel(parts[len(parts)-1], "->")
+
+	types := []string{}
+	for _, p := range parts {
+		types = append(types, strings.Join(strings.Fields(p), " "))
+	}
+
+	return types[:len(types)-1], types[len(types)-1:]
+}
+
+// haskellTypeVariables returns the type variables used in types, eg:
+// `a` and `b` in `(a -> b) -> [a]`
+func haskellTypeVariables(types []string) []string {
+	vars := []string{}
+	seen := map[string]bool{}
+	for _, t := range types {
+		for _, v := range haskellTypeVariable.FindAllString(t, -1) {
+			if !seen[v] {
+				seen[v] = true
+				vars = append(vars, v)
+			}
+		}
+	}
+	return vars
+}
+
+// splitTopLevel splits s on sep, ignoring separators within brackets
+func splitTopLevel(s, sep string) []string {
+	parts := []string{}
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		default:
+			if depth == 0 && strings.HasPrefix(s[i:], sep) {
+				parts = append(parts, s[start:i])
+				start = i + len(sep)
+				i += len(sep) - 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// stripHaskellComment removes trailing `--` comments from a line
+func stripHaskellComment(line string) string {
+	for i := strings.Index(line, "--"); i != -1; {
+		// `-->` and similar are operators, not comments
+		end := i + 2
+		for end < len(line) && line[end] == '-' {
+			end++
+		}
+		if end == len(line) || !strings.ContainsRune("!#$%&*+./<=>?@\\^|~:", rune(line[end])) {
+			return line[:i]
+		}
+
+		next := strings.Index(line[end:], "--")
+		if next == -1 {
+			break
+		}
+		i = end + next
+	}
+	return line
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// useHyperlinks decides if the locations should be hyperlinks for
+// -hyperlinks mode (auto, always or never), auto uses them in
+// terminals other than dumb ones
+func useHyperlinks(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		return os.Getenv("TERM") != "dumb" && stdoutIsTerminal(), nil
+	}
+	return false, fmt.Errorf("invalid hyperlinks mode '%s' (options: auto, always, never)", mode)
+}
+
+// linker builds the URLs of the results, with a Go template over the
+// result of every function (like -format) or as file:// URLs
+type linker struct {
+	tmpl *template.Template
+	host string
+}
+
+func newLinker(format string) (*linker, error) {
+	if format == "" {
+		host, _ := os.Hostname()
+		return &linker{host: host}, nil
+	}
+
+	tmpl, err := template.New("link").Funcs(template.FuncMap{"abs": filepath.Abs}).Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid link URL: %w", err)
+	}
+	return &linker{tmpl: tmpl}, nil
+}
+
+func (l *linker) url(f FuncWithDistance) (string, error) {
+	if l.tmpl == nil {
+		path, err := filepath.Abs(f.Func.Path)
+		if err != nil {
+			return "", err
+		}
+		return (&url.URL{Scheme: "file", Host: l.host, Path: filepath.ToSlash(path)}).String(), nil
+	}
+
+	var sb strings.Builder
+	if err := l.tmpl.Execute(&sb, newResult(f)); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// hyperlink makes text a link to url with an OSC 8 escape sequence
+func hyperlink(url, text string) string {
+	return "\033]8;;" + url + "\033\\" + text + "\033]8;;\033\\"
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreFiles are read in every directory, with the patterns of later
+// ones (and of deeper directories) winning
+var ignoreFiles = []string{".gitignore", ".ignore"}
+
+// ignoreRule is a pattern of an ignore file, which applies to the paths
+// below the directory of the file
+type ignoreRule struct {
+	base    string
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignorer skips the paths matched by patterns with the syntax of
+// .gitignore files
+type ignorer struct {
+	rules []ignoreRule
+}
+
+// newIgnorer skips .git directories and loads the ignore files of the
+// directories above root, up to the root of the git repository it is
+// in (if any)
+func newIgnorer(root string) (*ignorer, error) {
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	ig := &ignorer{}
+	if err := ig.add(abs, []string{".git/"}); err != nil {
+		return nil, err
+	}
+
+	parents := []string{}
+	for dir := filepath.Dir(abs); ; dir = filepath.Dir(dir) {
+		parents = append([]string{dir}, parents...)
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		if dir == filepath.Dir(dir) {
+			parents = nil // not in a repository
+			break
+		}
+	}
+	for _, dir := range parents {
+		if err := ig.load(dir); err != nil {
+			return nil, err
+		}
+	}
+	return ig, nil
+}
+
+// add adds patterns like the ones in ignore files, relative to base
+func (ig *ignorer) add(base string, patterns []string) error {
+	abs, err := filepath.Abs(base)
+	if err != nil {
+		return err
+	}
+	for _, p := range patterns {
+		if rule, ok := parseIgnorePattern(abs, p); ok {
+			ig.rules = append(ig.rules, rule)
+		}
+	}
+	return nil
+}
+
+// load adds the patterns in the ignore files of dir
+func (ig *ignorer) load(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range ignoreFiles {
+		file, err := os.Open(filepath.Join(abs, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if rule, ok := parseIgnorePattern(abs, scanner.Text()); ok {
+				ig.rules = append(ig.rules, rule)
+			}
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matches checks if path is matched by the patterns (and has to be
+// skipped), where the last pattern that matches it wins
+func (ig *ignorer) matches(path string, isDir bool) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+
+	ignored := false
+	for _, r := range ig.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		rel, err := filepath.Rel(r.base, abs)
+		if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if r.re.MatchString(filepath.ToSlash(rel)) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// parseIgnorePattern converts a line of an ignore file in base into a
+// rule, if it is not empty or a comment
+func parseIgnorePattern(base, line string) (ignoreRule, bool) {
+	rule := ignoreRule{base: base}
+
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:] // `\#` or `\!`
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+
+	// patterns with a slash (other than at the end) are relative to
+	// base, others match at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var sb strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case strings.HasPrefix(line[i:], "**/"):
+			sb.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(line[i:], "/**") && i+3 == len(line):
+			sb.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(line[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(line):
+			i++
+			sb.WriteString(regexp.QuoteMeta(line[i : i+1]))
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	prefix := "^"
+	if !anchored {
+		prefix = "^(.*/)?"
+	}
+	re, err := regexp.Compile(prefix + sb.String() + "$")
+	if err != nil {
+		return rule, false
+	}
+	rule.re = re
+	return rule, true
+}
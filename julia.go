@@ -0,0 +1,220 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// juliaMethod matches the start of a method definition up to the
+// opening paren of its parameters, eg: `function Base.show(` or
+// `@inline area(` (for the short `area(s::Square) = ...` form)
+var juliaMethod = regexp.MustCompile(`(?m)^[ \t]*(?:@[\w.]+[ \t]+)*(function[ \t]+)?([A-Za-z_][\w.!]*)[ \t]*(\{[^(){}]*\})?\(`)
+
+// parseJulia finds method definitions in Julia source. There is no
+// tree-sitter grammar for Julia available to us, so definitions are
+// found by looking for `function name(params)` or `name(params) =` at
+// the start of a line. Every method is added separately so that each
+// signature of a multiple dispatch function can be found. Keyword
+// arguments are labelled and parameters or results without a type
+// annotation are `_`.
+func parseJulia(sourceCode []byte, path string) []Func {
+	funcs := []Func{}
+	src := blankCommentsAndStrings(string(sourceCode), juliaSyntax)
+	lines := strings.Split(string(sourceCode), "\n")
+
+	for _, m := range juliaMethod.FindAllStringSubmatchIndex(src, -1) {
+		open := m[1] - 1
+		end := matchingBracket(src, open)
+		if end == -1 {
+			continue
+		}
+
+		// return type annotation and `where` clause
+		rest := src[end+1:]
+		if nl := strings.Index(rest, "\n"); nl != -1 {
+			rest = rest[:nl]
+		}
+		ret := "_"
+		if strings.HasPrefix(rest, "::") {
+			r := rest[2:]
+			if i := juliaTypeEnd(r); i != -1 {
+				r = r[:i]
+			}
+			ret = strings.TrimSpace(r)
+			rest = rest[2+len(r):]
+		}
+
+		// the short form needs an `=` (and not `==`) after the signature
+		if m[2] == -1 {
+			rest = strings.TrimSpace(rest)
+			if strings.HasPrefix(rest, "where") {
+				eq := juliaTypeEnd(rest[len("where"):])
+				if eq == -1 {
+					continue
+				}
+				rest = strings.TrimSpace(rest[len("where")+eq:])
+			}
+			if !strings.HasPrefix(rest, "=") || strings.HasPrefix(rest, "==") {
+				continue
+			}
+		}
+
+		start := m[4]
+		if m[2] != -1 {
+			start = m[2] // `function`
+		}
+
+		f := Func{
+			Path:   path,
+			Loc:    position(sourceCode, start),
+			Name:   src[m[4]:m[5]],
+			Args:   []string{},
+			Labels: []string{},
+			Rets:   []string{ret},
+		}
+		f.Doc = commentAbove(lines, f.Loc[0], []string{"#"}, `"""`, `"""`)
+
+		// the body of the short form is the rest of its line
+		bodyEnd := strings.IndexByte(src[end:], '\n')
+		if bodyEnd == -1 {
+			bodyEnd = len(src) - end
+		}
+		bodyEnd += end
+		if m[2] != -1 {
+			bodyEnd = juliaEnd(src, end+1)
+		}
+		if bodyEnd != -1 {
+			f.BodyLoc = bodyRows(sourceCode, start, bodyEnd)
+		}
+
+		params := splitTopLevel(src[open+1:end], ";")
+		juliaParams(params[0], false, &f)
+		if len(params) > 1 {
+			juliaParams(params[1], true, &f)
+		}
+
+		funcs = append(funcs, f)
+	}
+
+	return funcs
+}
+
+// juliaBlocks are the keywords of the blocks closed by `end`
+var juliaBlocks = map[string]bool{
+	"function": true, "if": true, "for": true, "while": true, "let": true,
+	"begin": true, "quote": true, "do": true, "try": true, "struct": true,
+	"module": true, "baremodule": true, "macro": true,
+}
+
+// juliaEnd returns the offset right after the `end` closing the block
+// that starts before from, or -1. Keywords in brackets (eg: `xs[end]`
+// or comprehensions) are skipped.
+func juliaEnd(src string, from int) int {
+	depth, brackets := 1, 0
+	for i := from; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == '(' || c == '[' || c == '{':
+			brackets++
+		case c == ')' || c == ']' || c == '}':
+			brackets--
+		case isJuliaIdent(c) && (i == 0 || !isJuliaIdent(src[i-1]) && src[i-1] != '.'):
+			j := i
+			for j < len(src) && isJuliaIdent(src[j]) {
+				j++
+			}
+			word := src[i:j]
+			i = j - 1
+			if brackets > 0 {
+				continue
+			}
+			if juliaBlocks[word] {
+				depth++
+			} else if word == "end" {
+				if depth--; depth == 0 {
+					return j
+				}
+			}
+		}
+	}
+	return -1
+}
+
+// juliaParams adds the parameters in text to f, labelling them if they
+// are keyword arguments
+func juliaParams(text string, keyword bool, f *Func) {
+	for _, p := range splitTopLevel(text, ",") {
+		p = strings.Join(strings.Fields(p), " ")
+		if p == "" {
+			continue
+		}
+
+		// default values
+		if parts := splitTopLevel(p, "="); len(parts) > 1 {
+			p = strings.TrimSpace(parts[0])
+		}
+
+		vararg := strings.HasSuffix(p, "...")
+		p = strings.TrimSuffix(p, "...")
+
+		name, t := p, "_"
+		if parts := splitTopLevel(p, "::"); len(parts) > 1 {
+			name, t = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		}
+		if vararg {
+			t += "..."
+		}
+
+		label := ""
+		if keyword {
+			label = name
+		}
+
+		f.Args = append(f.Args, t)
+		f.Labels = append(f.Labels, label)
+	}
+}
+
+// juliaTypeEnd returns the offset where the type at the start of s
+// ends (at a top level `where` or `=`), or -1 if it does not
+func juliaTypeEnd(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		case '=':
+			if depth == 0 && (i == 0 || s[i-1] != '<' && s[i-1] != '>' && s[i-1] != '=' && s[i-1] != '!') {
+				return i
+			}
+		case 'w':
+			if depth == 0 && strings.HasPrefix(s[i:], "where") && (i == 0 || s[i-1] == ' ') {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// juliaSyntax are the comments and literals of Julia, `'` after an
+// identifier or a closing bracket is the adjoint and not a character
+var juliaSyntax = lexicalSyntax{
+	lineComments:  []string{"#"},
+	blockComments: [][2]string{{"#=", "=#"}},
+	nested:        true,
+	quotes: []quoteSyntax{
+		{open: `"""`, close: `"""`, escape: true},
+		{open: "```", close: "```", escape: true},
+		{open: `"`, close: `"`, escape: true},
+		{open: "`", close: "`", escape: true},
+		{open: "'", close: "'", escape: true, oneLine: true, notAfter: func(prev byte) bool {
+			return isJuliaIdent(prev) || strings.ContainsRune(")]}.'", rune(prev))
+		}},
+	},
+	keepQuotes: true,
+}
+
+func isJuliaIdent(c byte) bool {
+	return c == '_' || c == '!' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
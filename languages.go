@@ -0,0 +1,1434 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/c"
+	"github.com/smacker/go-tree-sitter/cpp"
+	"github.com/smacker/go-tree-sitter/csharp"
+	"github.com/smacker/go-tree-sitter/elixir"
+	"github.com/smacker/go-tree-sitter/golang"
+	"github.com/smacker/go-tree-sitter/hcl"
+	"github.com/smacker/go-tree-sitter/java"
+	"github.com/smacker/go-tree-sitter/javascript"
+	"github.com/smacker/go-tree-sitter/kotlin"
+	"github.com/smacker/go-tree-sitter/lua"
+	"github.com/smacker/go-tree-sitter/ocaml"
+	"github.com/smacker/go-tree-sitter/php"
+	"github.com/smacker/go-tree-sitter/protobuf"
+	"github.com/smacker/go-tree-sitter/ruby"
+	"github.com/smacker/go-tree-sitter/scala"
+	"github.com/smacker/go-tree-sitter/swift"
+	"github.com/smacker/go-tree-sitter/typescript/tsx"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+	zig "github.com/tree-sitter-grammars/tree-sitter-zig/bindings/go"
+)
+
+// language describes how to find functions in files of a single
+// tree-sitter grammar.
+//
+// Queries has to contain the following entries:
+//   - function: matches a function, capturing @func, @name and
+//     optionally @body (defaults to the body field of @func). Types
+//     found after the start of the body are ignored so that nested
+//     functions do not leak into the signature.
+//   - input: run on every @func, captures one parameter type per match
+//   - output: run on every @func, captures one return type per match
+//   - generics (optional): run on every @func, captures the name of
+//     one type parameter per match
+//
+// Type, if set, renders a captured input/output node into the type
+// string used for matching. An empty string drops the capture.
+//
+// Signature, if set, is called with the @func node once the function
+// has been extracted and can be used to fill in types that queries
+// alone cannot express.
+//
+// Parse, if set, is used instead of tree-sitter to find functions for
+// languages that we do not have a grammar for.
+//
+// Files without a known extension are matched by their name
+// (Filenames), the interpreter in their shebang (Interpreters) or the
+// language set in a vim/emacs modeline (the name of the language or
+// one of its Aliases).
+//
+// Tests are patterns (see filepath.Match) for the names of test files,
+// whose functions are ranked below the rest or skipped with -no-tests.
+type language struct {
+	Title        string // name shown to users, eg: in the headers of -group-by language
+	Extensions   []string
+	Filenames    []string
+	Tests        []string
+	Interpreters []string
+	Aliases      []string
+	Grammar      func() *sitter.Language
+	Queries      map[string]string
+	Type         func(n *sitter.Node, sourceCode []byte) string
+	Signature    func(n *sitter.Node, sourceCode []byte, f *Func)
+	Parse        func(sourceCode []byte, path string) []Func
+	Version      string // changes with the grammar of external ones so that their cached functions are not used
+}
+
+const (
+	tsFunction = `(function_declaration name: (identifier) @name body: (_) @body) @func
+                  (generator_function_declaration name: (identifier) @name body: (_) @body) @func
+                  (function_signature name: (identifier) @name) @func
+                  (method_definition name: (property_identifier) @name body: (_) @body) @func
+                  (method_signature name: (property_identifier) @name) @func
+                  (variable_declarator name: (identifier) @name value: (arrow_function body: (_) @body)) @func
+                  (variable_declarator name: (identifier) @name value: (function_expression body: (_) @body)) @func`
+	tsInput = `(_ parameters: (formal_parameters [
+                   (required_parameter type: (type_annotation (_) @type))
+                   (optional_parameter type: (type_annotation (_) @type))]))`
+	tsOutput   = `(_ return_type: (type_annotation (_) @type))`
+	tsGenerics = "(type_parameter name: (type_identifier) @type)"
+)
+
+var languages = map[string]language{
+	"golang": {
+		Title:      "Go",
+		Extensions: []string{".go"},
+		Tests:      []string{"*_test.go"},
+		Aliases:    []string{"go"},
+		Grammar:    golang.GetLanguage,
+		Queries: map[string]string{
+			"function": `(function_declaration name: (identifier) @name) @func
+                         (method_declaration name: (field_identifier) @name) @func`,
+			"input": `(function_declaration parameters: (parameter_list [
+                          (parameter_declaration type: (_) @type)
+                          (variadic_parameter_declaration) @type]))
+                      (method_declaration parameters: (parameter_list [
+                          (parameter_declaration type: (_) @type)
+                          (variadic_parameter_declaration) @type]))`,
+			"output": `(function_declaration result: (parameter_list (parameter_declaration type: (_) @type)))
+                       (function_declaration result: [(type_identifier) (pointer_type) (slice_type)] @type)
+                       (method_declaration result: (parameter_list (parameter_declaration type: (_) @type)))
+                       (method_declaration result: [(type_identifier) (pointer_type) (slice_type)] @type)`,
+			"generics": "(type_parameter_declaration name: (identifier) @type)",
+		},
+		Type:      goType,
+		Signature: goSignature,
+	},
+	"typescript": {
+		Title:        "TypeScript",
+		Extensions:   []string{".ts", ".mts", ".cts"},
+		Tests:        []string{"*.test.*", "*.spec.*"},
+		Interpreters: []string{"ts-node", "deno"},
+		Aliases:      []string{"ts"},
+		Grammar:      typescript.GetLanguage,
+		Queries:      map[string]string{"function": tsFunction, "input": tsInput, "output": tsOutput, "generics": tsGenerics},
+		Type:         tsType,
+	},
+	"typescript_tsx": {
+		Title:      "TypeScript",
+		Extensions: []string{".tsx"},
+		Tests:      []string{"*.test.tsx", "*.spec.tsx"},
+		Grammar:    tsx.GetLanguage,
+		Queries:    map[string]string{"function": tsFunction, "input": tsInput, "output": tsOutput, "generics": tsGenerics},
+		Type:       tsType,
+	},
+	"javascript": {
+		// JavaScript has no type annotations, parameter names are
+		// used instead so that at least the arity can be matched.
+		// The output is always `_`.
+		Title:        "JavaScript",
+		Extensions:   []string{".js", ".jsx", ".mjs", ".cjs"},
+		Tests:        []string{"*.test.*", "*.spec.*"},
+		Filenames:    []string{"Jakefile"},
+		Interpreters: []string{"node", "nodejs"},
+		Aliases:      []string{"js", "js2", "js3"},
+		Grammar:      javascript.GetLanguage,
+		Queries: map[string]string{
+			"function": `(function_declaration name: (identifier) @name body: (_) @body) @func
+                         (generator_function_declaration name: (identifier) @name body: (_) @body) @func
+                         (method_definition name: (property_identifier) @name body: (_) @body) @func
+                         (variable_declarator name: (identifier) @name value: (arrow_function body: (_) @body)) @func
+                         (variable_declarator name: (identifier) @name value: (function_expression body: (_) @body)) @func`,
+			"input": `(formal_parameters [
+                          (identifier) @type
+                          (assignment_pattern left: (_) @type)
+                          (rest_pattern) @type
+                          (object_pattern) @type
+                          (array_pattern) @type])
+                      (arrow_function parameter: (identifier) @type)`,
+			"output": ``,
+		},
+		Signature: untypedSignature,
+	},
+	"java": {
+		Title:        "Java",
+		Extensions:   []string{".java"},
+		Tests:        []string{"*Test.java", "*Tests.java", "Test*.java"},
+		Interpreters: []string{"java"},
+		Grammar:      java.GetLanguage,
+		Queries: map[string]string{
+			"function": `(method_declaration name: (identifier) @name body: (block)? @body) @func
+                         (constructor_declaration name: (identifier) @name body: (constructor_body) @body) @func`,
+			"input": `(formal_parameters [
+                          (formal_parameter type: (_) @type)
+                          (spread_parameter (_) @type . (variable_declarator))])`,
+			// constructors return an instance of the class they are named after
+			"output": `(method_declaration type: (_) @type)
+                       (constructor_declaration name: (identifier) @type)`,
+			"generics": "(type_parameter (type_identifier) @type)",
+		},
+		Type: javaType,
+	},
+	"c": {
+		Title:      "C",
+		Extensions: []string{".c", ".h"},
+		Tests:      []string{"test_*.c", "*_test.c"},
+		Grammar:    c.GetLanguage,
+		Queries: map[string]string{
+			"function": cFunctionQuery("(identifier)", cDeclarators, []string{"declaration"}),
+			"input":    "(function_declarator parameters: (parameter_list [(parameter_declaration) (variadic_parameter) (identifier)] @type))",
+			"output":   "(function_definition type: (_) @type) (declaration type: (_) @type)",
+		},
+		Type: cType,
+	},
+	"cpp": {
+		Title:      "C++",
+		Extensions: []string{".cpp", ".cc", ".cxx", ".c++", ".hpp", ".hh", ".hxx", ".h++"},
+		Tests:      []string{"test_*", "*_test.*", "*_unittest.*"},
+		Aliases:    []string{"c++"},
+		Grammar:    cpp.GetLanguage,
+		Queries: map[string]string{
+			"function": cFunctionQuery(
+				"[(identifier) (field_identifier) (qualified_identifier) (destructor_name) (operator_name)]",
+				append(cDeclarators, "(reference_declarator %s)"),
+				[]string{"declaration", "field_declaration"},
+			),
+			"input": `(function_declarator parameters: (parameter_list [
+                          (parameter_declaration)
+                          (optional_parameter_declaration)
+                          (variadic_parameter_declaration)] @type))`,
+			"output": `(function_definition type: (_) @type)
+                       (declaration type: (_) @type)
+                       (field_declaration type: (_) @type)
+                       (function_declarator (trailing_return_type (_) @type))`,
+		},
+		Type: cType,
+	},
+	"ruby": {
+		// Ruby is untyped: parameter names are used as inputs and the
+		// output is `_` unless there is a sorbet signature.
+		Title:        "Ruby",
+		Extensions:   []string{".rb", ".rake", ".gemspec"},
+		Tests:        []string{"*_test.rb", "*_spec.rb", "test_*.rb"},
+		Filenames:    []string{"Rakefile", "Gemfile", "Guardfile", "Podfile", "Vagrantfile", "Brewfile", "Fastfile", "Appfile", "Capfile", "Dangerfile", "Thorfile", ".irbrc", ".pryrc"},
+		Interpreters: []string{"ruby", "jruby", "truffleruby"},
+		Aliases:      []string{"rb", "enh-ruby"},
+		Grammar:      ruby.GetLanguage,
+		Queries: map[string]string{
+			"function": `(method name: (_) @name) @func
+                         (singleton_method name: (_) @name) @func`,
+			"input": `(method_parameters [
+                          (identifier) @type
+                          (optional_parameter name: (_) @type)
+                          (keyword_parameter name: (_) @type)
+                          (splat_parameter) @type
+                          (hash_splat_parameter) @type
+                          (block_parameter) @type])`,
+			"output": ``,
+		},
+		Signature: rubySignature,
+	},
+	"kotlin": {
+		Title:        "Kotlin",
+		Extensions:   []string{".kt", ".kts"},
+		Tests:        []string{"*Test.kt", "*Tests.kt"},
+		Interpreters: []string{"kotlin", "kscript"},
+		Grammar:      kotlin.GetLanguage,
+		Queries: map[string]string{
+			"function": "(function_declaration (simple_identifier) @name (function_body)? @body) @func",
+			"input":    "(function_value_parameters (parameter (simple_identifier) . (_) @type))",
+			"output":   ``,
+			"generics": "(type_parameter (type_identifier) @type)",
+		},
+		Type:      kotlinType,
+		Signature: kotlinSignature,
+	},
+	"csharp": {
+		Title:        "C#",
+		Extensions:   []string{".cs"},
+		Tests:        []string{"*Test.cs", "*Tests.cs"},
+		Interpreters: []string{"dotnet-script"},
+		Aliases:      []string{"cs"},
+		Grammar:      csharp.GetLanguage,
+		Queries: map[string]string{
+			// properties are indexed as functions without inputs if
+			// they can be read
+			"function": `(method_declaration name: (identifier) @name body: (_)? @body) @func
+                         (local_function_statement name: (identifier) @name body: (_)? @body) @func
+                         (constructor_declaration name: (identifier) @name body: (_)? @body) @func
+                         (property_declaration name: (identifier) @name accessors: (accessor_list (accessor_declaration "get"))) @func
+                         (property_declaration name: (identifier) @name value: (arrow_expression_clause)) @func`,
+			"input": "(parameter_list [(parameter type: (_)) @type type: (array_type) @type])",
+			"output": `(method_declaration returns: (_) @type)
+                       (local_function_statement type: (_) @type)
+                       (property_declaration type: (_) @type)
+                       (constructor_declaration name: (identifier) @type)`,
+			"generics": "(type_parameter name: (identifier) @type)",
+		},
+		Type: csharpType,
+	},
+	"swift": {
+		Title:        "Swift",
+		Extensions:   []string{".swift"},
+		Tests:        []string{"*Test.swift", "*Tests.swift"},
+		Interpreters: []string{"swift"},
+		Grammar:      swift.GetLanguage,
+		Queries: map[string]string{
+			// capturing the body makes compiling the query extremely slow
+			"function": `(function_declaration name: (simple_identifier) @name) @func
+                         (init_declaration "init" @name) @func
+                         (protocol_function_declaration name: (simple_identifier) @name) @func`,
+			"input": `(function_declaration (parameter) @type)
+                      (init_declaration (parameter) @type)
+                      (protocol_function_declaration (parameter) @type)`,
+			"output":   ``,
+			"generics": "(type_parameter (type_identifier) @type)",
+		},
+		Type:      swiftType,
+		Signature: swiftSignature,
+	},
+	"scala": {
+		// curried and implicit/using parameter lists are flattened
+		// into a single list of inputs
+		Title:        "Scala",
+		Extensions:   []string{".scala", ".sc"},
+		Tests:        []string{"*Test.scala", "*Spec.scala", "*Suite.scala"},
+		Interpreters: []string{"scala", "amm", "scala-cli"},
+		Grammar:      scala.GetLanguage,
+		Queries: map[string]string{
+			"function": `(function_definition name: (identifier) @name) @func
+                         (function_declaration name: (identifier) @name) @func`,
+			"input": "(parameters (parameter type: (_) @type))",
+			"output": `(function_definition return_type: (_) @type)
+                       (function_declaration return_type: (_) @type)`,
+			"generics": "(type_parameters name: (identifier) @type)",
+		},
+	},
+	"haskell": {
+		Title:        "Haskell",
+		Extensions:   []string{".hs"},
+		Tests:        []string{"*Spec.hs", "*Test.hs"},
+		Interpreters: []string{"runghc", "runhaskell", "stack", "cabal"},
+		Aliases:      []string{"hs"},
+		Parse:        parseHaskell,
+	},
+	"zig": {
+		// error unions are kept as a single output, eg: `!void`
+		Title:      "Zig",
+		Extensions: []string{".zig"},
+		Grammar:    func() *sitter.Language { return sitter.NewLanguage(zig.Language()) },
+		Queries: map[string]string{
+			"function": "(function_declaration name: (identifier) @name) @func",
+			"input":    "(function_declaration (parameters (parameter type: (_) @type)))",
+			"output":   "(function_declaration type: (_) @type)",
+		},
+	},
+	"elixir": {
+		// parameter names are used as inputs unless there is a @spec
+		Title:        "Elixir",
+		Extensions:   []string{".ex", ".exs"},
+		Tests:        []string{"*_test.exs"},
+		Interpreters: []string{"elixir"},
+		Grammar:      elixir.GetLanguage,
+		Queries: map[string]string{
+			"function": `((call
+                            target: (identifier) @kind
+                            (arguments . [
+                              (call target: (identifier) @name)
+                              (binary_operator left: (call target: (identifier) @name) operator: "when")
+                              (identifier) @name])) @func
+                          (#match? @kind "^defp?$"))`,
+		},
+		Signature: elixirSignature,
+	},
+	"php": {
+		Title:        "PHP",
+		Extensions:   []string{".php"},
+		Tests:        []string{"*Test.php"},
+		Interpreters: []string{"php"},
+		Grammar:      php.GetLanguage,
+		Queries: map[string]string{
+			"function": `(function_definition name: (name) @name) @func
+                         (method_declaration name: (name) @name) @func`,
+			"input": `(formal_parameters [
+                          (simple_parameter)
+                          (variadic_parameter)
+                          (property_promotion_parameter)] @type)`,
+			"output": `(function_definition return_type: (_) @type)
+                       (method_declaration return_type: (_) @type)`,
+		},
+		Type: phpType,
+	},
+	"protobuf": {
+		// rpc methods are shown as `Service.Method`
+		Title:      "Protocol Buffers",
+		Extensions: []string{".proto"},
+		Aliases:    []string{"proto"},
+		Grammar:    protobuf.GetLanguage,
+		Queries: map[string]string{
+			"function": `(rpc (rpc_name) @name) @func`,
+		},
+		Signature: protoSignature,
+	},
+	"hcl": {
+		// resource, data and module blocks use their arguments as
+		// inputs, variables and outputs are `() -> ( type/value )`
+		Title:      "HCL",
+		Extensions: []string{".tf", ".tfvars", ".hcl"},
+		Aliases:    []string{"terraform"},
+		Grammar:    hcl.GetLanguage,
+		Queries: map[string]string{
+			"function": `(config_file (body (block . (identifier) @name
+                             (#match? @name "^(resource|data|module|variable|output)$")) @func))`,
+		},
+		Signature: hclSignature,
+	},
+	"lua": {
+		// parameter names are used as inputs unless there are
+		// LuaLS/EmmyLua annotations
+		Title:        "Lua",
+		Extensions:   []string{".lua"},
+		Tests:        []string{"*_spec.lua", "*_test.lua"},
+		Interpreters: []string{"lua", "luajit"},
+		Grammar:      lua.GetLanguage,
+		Queries: map[string]string{
+			"function": `(function_statement name: (_) @name) @func
+                         (variable_declaration name: (variable_declarator) @name value: (function)) @func`,
+		},
+		Signature: luaSignature,
+	},
+	"dart": {
+		Title:        "Dart",
+		Extensions:   []string{".dart"},
+		Tests:        []string{"*_test.dart"},
+		Interpreters: []string{"dart"},
+		Parse:        parseDart,
+	},
+	"erlang": {
+		Title:        "Erlang",
+		Extensions:   []string{".erl", ".hrl"},
+		Tests:        []string{"*_SUITE.erl", "*_tests.erl"},
+		Interpreters: []string{"escript"},
+		Parse:        parseErlang,
+	},
+	"julia": {
+		Title:        "Julia",
+		Extensions:   []string{".jl"},
+		Tests:        []string{"runtests.jl"},
+		Interpreters: []string{"julia"},
+		Parse:        parseJulia,
+	},
+	"graphql": {
+		Title:      "GraphQL",
+		Extensions: []string{".graphql", ".graphqls", ".gql"},
+		Aliases:    []string{"gql"},
+		Parse:      parseGraphQL,
+	},
+	"sql": {
+		Title:      "SQL",
+		Extensions: []string{".sql", ".psql", ".pgsql", ".plsql"},
+		Aliases:    []string{"plsql", "tsql", "mysql", "postgresql"},
+		Parse:      parseSQL,
+	},
+	"nim": {
+		Title:        "Nim",
+		Extensions:   []string{".nim", ".nims"},
+		Interpreters: []string{"nim"},
+		Parse:        parseNim,
+	},
+	"ocaml": ocamlLanguage,
+	"ocaml_interface": {
+		Title:      "OCaml",
+		Extensions: []string{".mli"},
+		Parse:      parseOCamlInterface,
+	},
+}
+
+// ocamlLanguage indexes let bindings with type annotations (on any of
+// the parameters or the result), value specifications and externals.
+var ocamlLanguage = language{
+	Title:        "OCaml",
+	Extensions:   []string{".ml"},
+	Interpreters: []string{"ocaml"},
+	Aliases:      []string{"tuareg"},
+	Grammar:      ocaml.GetLanguage,
+	Queries: map[string]string{
+		"function": `(let_binding pattern: (value_name) @name (parameter pattern: (typed_pattern))) @func
+                     (let_binding pattern: (value_name) @name (parameter (_) (_))) @func
+                     (let_binding pattern: (value_name) @name ":") @func
+                     (value_specification (value_name) @name) @func
+                     (external (value_name) @name) @func`,
+	},
+	Signature: ocamlSignature,
+}
+
+// selectLanguages removes all languages but the ones in names (or
+// their variants like typescript_tsx for typescript) from languages
+func selectLanguages(names []string) error {
+	selected := map[string]bool{}
+	for _, n := range names {
+		n = strings.ToLower(strings.TrimSpace(n))
+		found := false
+		for name, l := range languages {
+			match := name == n || strings.HasPrefix(name, n+"_")
+			for _, a := range l.Aliases {
+				match = match || a == n
+			}
+			if match {
+				selected[name] = true
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("unknown language %s", n)
+		}
+	}
+
+	for name := range languages {
+		if !selected[name] {
+			delete(languages, name)
+		}
+	}
+	return nil
+}
+
+// languageTitle is the Title of the language name, or its name if it
+// has none (eg: external grammars)
+func languageTitle(name string) string {
+	if l, ok := languages[name]; ok && l.Title != "" {
+		return l.Title
+	}
+	return name
+}
+
+// languageNames are the names of the languages for -lang, without the
+// variants (eg: typescript_tsx) and aliases
+func languageNames() []string {
+	names := map[string]bool{}
+	for name := range languages {
+		base, _, _ := strings.Cut(name, "_")
+		names[base] = true
+	}
+	return sortedKeys(names)
+}
+
+// isTestFile checks if path is a test file of the language lang
+func isTestFile(lang, path string) bool {
+	for _, pattern := range languages[lang].Tests {
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// getLanguage finds the language of the file at path by its extension
+// or name, and otherwise (for files without an extension, like scripts)
+// from the shebang or modeline in its first lines
+func getLanguage(path string) string {
+	filename := filepath.Base(path)
+	ext := filepath.Ext(filename)
+	for name, l := range languages {
+		for _, e := range l.Extensions {
+			if e == ext {
+				return name
+			}
+		}
+		for _, f := range l.Filenames {
+			if f == filename {
+				return name
+			}
+		}
+	}
+
+	// reading every data file, image or lockfile would slow down the walk
+	if ext != "" {
+		return ""
+	}
+	head, err := readHead(path, 1024)
+	if err != nil {
+		return ""
+	}
+
+	lines := strings.SplitN(string(head), "\n", 6)
+	if len(lines) > 5 {
+		lines = lines[:5]
+	}
+
+	if strings.HasPrefix(lines[0], "#!") {
+		interpreter := shebangInterpreter(lines[0])
+		for name, l := range languages {
+			for _, i := range l.Interpreters {
+				if i == interpreter {
+					return name
+				}
+			}
+		}
+	}
+
+	for _, line := range lines {
+		mode := modelineLanguage(line)
+		if mode == "" {
+			continue
+		}
+		for name, l := range languages {
+			if name == mode {
+				return name
+			}
+			for _, a := range l.Aliases {
+				if a == mode {
+					return name
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// readHead reads (up to) the first n bytes of the file at path
+func readHead(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	buf := make([]byte, n)
+	read, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return buf[:read], nil
+}
+
+var (
+	// vimModeline matches modelines like `vim: set ft=ruby:` or `vi: filetype=lua`
+	vimModeline = regexp.MustCompile(`\b(?:vi|vim|ex):.*\b(?:ft|filetype|syntax)=([\w+-]+)`)
+
+	// emacsModeline matches `-*- mode: ruby -*-` or `-*- ruby -*-`
+	emacsModeline = regexp.MustCompile(`-\*-\s*(?:.*\bmode:\s*)?([\w+-]+)\s*(?:;.*)?-\*-`)
+
+	// interpreterVersion matches versions in interpreter names like `lua5.4`
+	interpreterVersion = regexp.MustCompile(`[\d.]+$`)
+)
+
+// shebangInterpreter returns the name of the interpreter (without any
+// version) in a shebang line, eg: `#!/usr/bin/env -S ruby2.7 -w` is ruby
+func shebangInterpreter(line string) string {
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") && !strings.Contains(f, "=") {
+				interpreter = filepath.Base(f)
+				break
+			}
+		}
+	}
+
+	return interpreterVersion.ReplaceAllString(interpreter, "")
+}
+
+// modelineLanguage returns the (lowercased) language set in a vim or
+// emacs modeline in line, if there is one
+func modelineLanguage(line string) string {
+	if m := vimModeline.FindStringSubmatch(line); m != nil {
+		return strings.ToLower(m[1])
+	}
+	if m := emacsModeline.FindStringSubmatch(line); m != nil {
+		return strings.TrimSuffix(strings.ToLower(m[1]), "-mode")
+	}
+	return ""
+}
+
+// cDeclarators are the declarators a function declarator can be wrapped
+// in when the function returns a pointer.
+var cDeclarators = []string{
+	"%s",
+	"(pointer_declarator declarator: %s)",
+	"(pointer_declarator declarator: (pointer_declarator declarator: %s))",
+}
+
+// cFunctionQuery matches C style function definitions and prototypes
+// (declared with one of the decls node types) named by name whose
+// function declarator is wrapped in one of declarators.
+func cFunctionQuery(name string, declarators []string, decls []string) string {
+	q := ""
+	for _, d := range declarators {
+		decl := fmt.Sprintf(d, fmt.Sprintf("(function_declarator declarator: %s @name)", name))
+		q += fmt.Sprintf("(function_definition declarator: %s body: (_)? @body) @func\n", decl)
+		for _, dc := range decls {
+			q += fmt.Sprintf("(%s declarator: %s) @func\n", dc, decl)
+		}
+	}
+	return q
+}
+
+// javaType renders the type of varargs (`String... args`) as `...String`
+func javaType(n *sitter.Node, sourceCode []byte) string {
+	if p := n.Parent(); p != nil && p.Type() == "spread_parameter" {
+		return "..." + n.Content(sourceCode)
+	}
+	return n.Content(sourceCode)
+}
+
+// tsType renders the type of rest parameters (`...xs: string[]`) as
+// the type of their elements, `...string`
+func tsType(n *sitter.Node, sourceCode []byte) string {
+	annotation := n.Parent()
+	if annotation == nil || annotation.Type() != "type_annotation" {
+		return n.Content(sourceCode)
+	}
+	p := annotation.Parent()
+	if p == nil || p.Type() != "required_parameter" {
+		return n.Content(sourceCode)
+	}
+	if pattern := p.ChildByFieldName("pattern"); pattern == nil || pattern.Type() != "rest_pattern" {
+		return n.Content(sourceCode)
+	}
+
+	t := n
+	if t.Type() == "readonly_type" && t.NamedChildCount() == 1 {
+		t = t.NamedChild(0)
+	}
+	if t.Type() == "array_type" && t.NamedChildCount() == 1 {
+		return "..." + t.NamedChild(0).Content(sourceCode)
+	}
+	if t.Type() == "generic_type" {
+		name, args := t.ChildByFieldName("name"), t.ChildByFieldName("type_arguments")
+		if name != nil && args != nil && args.NamedChildCount() == 1 &&
+			(name.Content(sourceCode) == "Array" || name.Content(sourceCode) == "ReadonlyArray") {
+			return "..." + args.NamedChild(0).Content(sourceCode)
+		}
+	}
+	return "..." + n.Content(sourceCode)
+}
+
+// cType renders C/C++ parameters without their names (`const char *s`
+// becomes `const char *`) and return types including the pointers
+// and references that are part of the function declarator.
+func cType(n *sitter.Node, sourceCode []byte) string {
+	switch n.Type() {
+	case "variadic_parameter":
+		return "..."
+	case "identifier":
+		// untyped K&R style parameter
+		return n.Content(sourceCode)
+	case "parameter_declaration", "optional_parameter_declaration", "variadic_parameter_declaration":
+		start, end := n.StartByte(), n.EndByte()
+		if dv := n.ChildByFieldName("default_value"); dv != nil {
+			end = dv.StartByte()
+		}
+
+		t := string(sourceCode[start:end])
+		if id := declaratorName(n); id != nil && id.EndByte() <= end {
+			t = string(sourceCode[start:id.StartByte()]) + string(sourceCode[id.EndByte():end])
+		}
+
+		t = strings.TrimSuffix(strings.TrimSpace(t), "=")
+
+		t = strings.Join(strings.Fields(t), " ")
+		if t == "void" {
+			return ""
+		}
+		return t
+	}
+
+	// return type: prefix qualifiers of the declaration and pointers
+	// wrapping the function declarator are part of the type
+	parent := n.Parent()
+	switch parent.Type() {
+	case "trailing_return_type":
+		return n.Content(sourceCode)
+	case "declaration":
+		// parameter declarations of K&R style definitions
+		if parent.Parent().Type() == "function_definition" {
+			return ""
+		}
+	}
+
+	t := n.Content(sourceCode)
+	for i := 0; i < int(parent.NamedChildCount()); i++ {
+		c := parent.NamedChild(i)
+		if c.StartByte() >= n.StartByte() {
+			break
+		}
+		if c.Type() == "type_qualifier" {
+			t = c.Content(sourceCode) + " " + t
+		}
+	}
+
+	stars := ""
+	d := parent.ChildByFieldName("declarator")
+wrappers:
+	for d != nil {
+		switch d.Type() {
+		case "pointer_declarator":
+			stars += "*"
+			d = d.ChildByFieldName("declarator")
+		case "reference_declarator":
+			stars += d.Child(0).Type()
+			d = d.NamedChild(0)
+		case "function_declarator":
+			// the trailing return type is captured on its own
+			last := d.NamedChild(int(d.NamedChildCount()) - 1)
+			if t == "auto" && last.Type() == "trailing_return_type" {
+				return ""
+			}
+			break wrappers
+		default:
+			break wrappers
+		}
+	}
+	if stars != "" {
+		t += " " + stars
+	}
+
+	return t
+}
+
+// declaratorName finds the identifier declared by a declaration by
+// following its (possibly nested) declarators.
+func declaratorName(n *sitter.Node) *sitter.Node {
+	d := n.ChildByFieldName("declarator")
+	for d != nil {
+		switch d.Type() {
+		case "identifier", "field_identifier":
+			return d
+		case "parenthesized_declarator", "reference_declarator":
+			d = d.NamedChild(0)
+		default:
+			d = d.ChildByFieldName("declarator")
+		}
+	}
+	return nil
+}
+
+// untypedSignature marks the output of functions in languages without
+// type annotations as unknown.
+func untypedSignature(n *sitter.Node, sourceCode []byte, f *Func) {
+	f.Rets = []string{"_"}
+}
+
+// rubySignature uses the sorbet `sig` block right before a method (if
+// any) to find the types of its parameters and its return type.
+func rubySignature(n *sitter.Node, sourceCode []byte, f *Func) {
+	sig := n.PrevNamedSibling()
+	if sig == nil || !isRubyCall(sig, "sig", sourceCode) {
+		untypedSignature(n, sourceCode, f)
+		return
+	}
+
+	params := map[string]string{}
+	walk(sig, func(c *sitter.Node) {
+		switch {
+		case isRubyCall(c, "params", sourceCode):
+			args := c.ChildByFieldName("arguments")
+			for i := 0; args != nil && i < int(args.NamedChildCount()); i++ {
+				p := args.NamedChild(i)
+				if p.Type() != "pair" {
+					continue
+				}
+				key := strings.TrimSuffix(p.ChildByFieldName("key").Content(sourceCode), ":")
+				params[key] = p.ChildByFieldName("value").Content(sourceCode)
+			}
+		case isRubyCall(c, "returns", sourceCode):
+			if args := c.ChildByFieldName("arguments"); args != nil && args.NamedChildCount() > 0 {
+				f.Rets = []string{args.NamedChild(0).Content(sourceCode)}
+			}
+		}
+	})
+
+	for i, a := range f.Args {
+		if t, ok := params[strings.TrimLeft(a, "*&")]; ok {
+			f.Args[i] = t
+		}
+	}
+}
+
+func isRubyCall(n *sitter.Node, name string, sourceCode []byte) bool {
+	if n.Type() != "call" {
+		return false
+	}
+	m := n.ChildByFieldName("method")
+	return m != nil && m.Content(sourceCode) == name
+}
+
+// walk calls fn with n and all its descendants
+func walk(n *sitter.Node, fn func(*sitter.Node)) {
+	fn(n)
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		walk(n.NamedChild(i), fn)
+	}
+}
+
+// goType renders variadic parameters as `...T`
+func goType(n *sitter.Node, sourceCode []byte) string {
+	if n.Type() == "variadic_parameter_declaration" {
+		if t := n.ChildByFieldName("type"); t != nil {
+			return "..." + t.Content(sourceCode)
+		}
+	}
+	return n.Content(sourceCode)
+}
+
+// goSignature sets the receiver of methods to the name of the type
+// (without the pointer), eg: `Server` for `func (s *Server) Start()`.
+// The type parameters of generic receivers (`*Set[K]`) are added to
+// the type parameters of the method.
+func goSignature(n *sitter.Node, sourceCode []byte, f *Func) {
+	receiver := n.ChildByFieldName("receiver")
+	if receiver == nil || receiver.NamedChildCount() == 0 {
+		return
+	}
+
+	t := receiver.NamedChild(0).ChildByFieldName("type")
+	if t != nil && t.Type() == "pointer_type" {
+		t = t.NamedChild(0)
+	}
+	if t != nil && t.Type() == "generic_type" {
+		if args := t.ChildByFieldName("type_arguments"); args != nil {
+			for i := 0; i < int(args.NamedChildCount()); i++ {
+				f.TypeParams = append(f.TypeParams, args.NamedChild(i).Content(sourceCode))
+			}
+		}
+		t = t.ChildByFieldName("type")
+	}
+	if t != nil {
+		f.Receiver = t.Content(sourceCode)
+	}
+}
+
+// csharpType renders the type of a parameter with its `ref`/`out`
+// modifier, `params T[]` as `...T` and `Nullable<T>` as `T?`
+func csharpType(n *sitter.Node, sourceCode []byte) string {
+	if n.Type() == "array_type" && n.Parent() != nil && n.Parent().Type() == "parameter_list" {
+		// the grammar puts `params` arrays directly in the list
+		if elem := n.ChildByFieldName("type"); elem != nil {
+			return "..." + csharpNullable(elem, sourceCode)
+		}
+	}
+	if n.Type() != "parameter" {
+		return csharpNullable(n, sourceCode)
+	}
+
+	var mods []string
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		c := n.NamedChild(i)
+		if c.Type() != "modifier" {
+			continue
+		}
+		// in, this and scoped do not change what can be passed
+		if m := c.Content(sourceCode); m == "ref" || m == "out" {
+			mods = append(mods, m)
+		}
+	}
+	return strings.Join(append(mods, csharpNullable(n.ChildByFieldName("type"), sourceCode)), " ")
+}
+
+func csharpNullable(n *sitter.Node, sourceCode []byte) string {
+	t := strings.Join(strings.Fields(n.Content(sourceCode)), " ")
+	if strings.HasPrefix(t, "Nullable<") && strings.HasSuffix(t, ">") {
+		return t[len("Nullable<"):len(t)-1] + "?"
+	}
+	if strings.HasPrefix(t, "System.Nullable<") && strings.HasSuffix(t, ">") {
+		return t[len("System.Nullable<"):len(t)-1] + "?"
+	}
+	return t
+}
+
+// kotlinType renders the type of vararg parameters (`vararg xs: Int`)
+// as `...Int`
+func kotlinType(n *sitter.Node, sourceCode []byte) string {
+	p := n.Parent()
+	if p == nil || p.Type() != "parameter" {
+		return n.Content(sourceCode)
+	}
+	if m := p.PrevNamedSibling(); m != nil && m.Type() == "parameter_modifiers" {
+		for i := 0; i < int(m.NamedChildCount()); i++ {
+			if m.NamedChild(i).Content(sourceCode) == "vararg" {
+				return "..." + n.Content(sourceCode)
+			}
+		}
+	}
+	return n.Content(sourceCode)
+}
+
+// kotlinSignature finds the receiver type of extension functions and
+// the return type. The grammar has no fields to tell these apart from
+// other types, so they are found by their position around the name.
+func kotlinSignature(n *sitter.Node, sourceCode []byte, f *Func) {
+	seenName := false
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		c := n.NamedChild(i)
+		switch {
+		case c.Type() == "simple_identifier":
+			seenName = true
+		case c.Type() == "function_body":
+			return
+		case strings.HasSuffix(c.Type(), "_type") && c.Type() != "function_type_parameters":
+			if !seenName {
+				f.Receiver = c.Content(sourceCode)
+			} else {
+				f.Rets = []string{c.Content(sourceCode)}
+			}
+		}
+	}
+}
+
+// swiftType renders a parameter without its labels, but including
+// attributes and variadic dots (`_ cb: @escaping () -> Void` becomes
+// `@escaping () -> Void`).
+func swiftType(n *sitter.Node, sourceCode []byte) string {
+	for i := 0; i < int(n.ChildCount()); i++ {
+		if n.Child(i).Type() == ":" && i+1 < int(n.ChildCount()) {
+			return strings.TrimSpace(string(sourceCode[n.Child(i+1).StartByte():n.EndByte()]))
+		}
+	}
+	return n.Content(sourceCode)
+}
+
+// swiftSignature records argument labels, the return type and whether
+// the function throws. Initializers return the type they are defined
+// in and functions in extensions get the extended type as receiver.
+func swiftSignature(n *sitter.Node, sourceCode []byte, f *Func) {
+	f.Labels = []string{}
+	throws := false
+	for i := 0; i < int(n.ChildCount()); i++ {
+		c := n.Child(i)
+		switch c.Type() {
+		case "parameter":
+			label := c.ChildByFieldName("external_name")
+			if label == nil {
+				label = c.ChildByFieldName("name")
+			}
+			l := label.Content(sourceCode)
+			if l == "_" {
+				l = ""
+			}
+			f.Labels = append(f.Labels, l)
+		case "throws":
+			throws = true
+		case "->":
+			if ret := n.Child(i + 1); ret != nil {
+				f.Rets = append(f.Rets, ret.Content(sourceCode))
+			}
+		}
+	}
+
+	for p := n.Parent(); p != nil; p = p.Parent() {
+		if p.Type() != "class_declaration" {
+			continue
+		}
+
+		name := p.ChildByFieldName("name").Content(sourceCode)
+		if n.Type() == "init_declaration" {
+			f.Rets = append(f.Rets, name)
+		} else if p.ChildByFieldName("declaration_kind").Content(sourceCode) == "extension" {
+			f.Receiver = name
+		}
+		break
+	}
+
+	// errors are treated as an extra output, like they would be in Go
+	if throws {
+		f.Rets = append(f.Rets, "throws")
+	}
+}
+
+// parseOCamlInterface parses .mli files by wrapping them in a module
+// type, as the grammar we have can only parse implementations.
+func parseOCamlInterface(sourceCode []byte, path string) []Func {
+	prefix := "module type M = sig "
+	wrapped := append([]byte(prefix), sourceCode...)
+	wrapped = append(wrapped, []byte("\nend\n")...)
+
+	funcs := parseFuncs(wrapped, path, ocamlLanguage)
+	for i := range funcs {
+		loc := funcs[i].Loc
+		if loc[0] == 0 {
+			loc[1] -= len(prefix)
+		}
+		if loc[3] == 0 {
+			loc[4] -= len(prefix)
+		}
+		loc[2] -= len(prefix)
+	}
+	return funcs
+}
+
+// ocamlSignature collects the types of the parameters and the result of
+// a binding. A type annotation on a binding without parameters (and in
+// value specifications) is split on `->` into inputs and outputs.
+// Parameters and results without annotations are `_`.
+func ocamlSignature(n *sitter.Node, sourceCode []byte, f *Func) {
+	f.Args, f.Labels, f.Rets = []string{}, []string{}, []string{}
+
+	var annotation *sitter.Node
+	for i := 0; i < int(n.ChildCount()); i++ {
+		c := n.Child(i)
+		switch {
+		case c.Type() == "parameter":
+			label, t := ocamlParameter(c, sourceCode)
+			f.Args = append(f.Args, t)
+			f.Labels = append(f.Labels, label)
+		case c.Type() == ":" && i+1 < int(n.ChildCount()):
+			annotation = n.Child(i + 1)
+		case n.Type() != "let_binding" && c.IsNamed() && c.Type() != "value_name" && annotation == nil:
+			annotation = c
+		}
+	}
+
+	switch {
+	case annotation == nil:
+		f.Rets = append(f.Rets, "_")
+	case len(f.Args) > 0:
+		f.Rets = append(f.Rets, annotation.Content(sourceCode))
+	default:
+		for annotation.Type() == "function_type" {
+			label, t := ocamlParameter(annotation.NamedChild(0), sourceCode)
+			f.Args = append(f.Args, t)
+			f.Labels = append(f.Labels, label)
+			annotation = annotation.NamedChild(1)
+		}
+		f.Rets = append(f.Rets, annotation.Content(sourceCode))
+	}
+}
+
+// ocamlParameter returns the label and type of a parameter of a let
+// binding or the domain of a function type
+func ocamlParameter(n *sitter.Node, sourceCode []byte) (string, string) {
+	prefix := ""
+	if strings.HasPrefix(n.Content(sourceCode), "?") {
+		prefix = "?"
+	}
+
+	switch {
+	case n.Type() == "typed_label":
+		return prefix + n.NamedChild(0).Content(sourceCode), n.NamedChild(1).Content(sourceCode)
+	case n.Type() != "parameter":
+		return "", n.Content(sourceCode)
+	case n.NamedChildCount() == 0:
+		return "", n.Content(sourceCode) // ()
+	}
+
+	// labelled: ~f, ?f, ~(f : t), ?(f : t = v) or ~l:(f : t)
+	label, t := "", "_"
+	p := n.ChildByFieldName("pattern")
+	switch {
+	case p != nil && p.Type() == "typed_pattern":
+		t = p.NamedChild(int(p.NamedChildCount()) - 1).Content(sourceCode)
+	case p != nil && p.Type() == "unit":
+		t = "unit" // like in interfaces
+	}
+	for i := 0; i < int(n.ChildCount()); i++ {
+		c := n.Child(i)
+		if c.Type() == "=" {
+			break // the default value
+		}
+		switch c.Type() {
+		case "label_name":
+			label = c.Content(sourceCode)
+		case ":":
+			if i+1 < int(n.ChildCount()) && n.Child(i+1).IsNamed() && n.FieldNameForChild(i+1) != "pattern" {
+				t = n.Child(i + 1).Content(sourceCode)
+			}
+		}
+	}
+
+	if label == "" && p != nil && (prefix != "" || strings.HasPrefix(n.Content(sourceCode), "~")) {
+		label = p.Content(sourceCode)
+		if p.Type() == "typed_pattern" {
+			label = p.NamedChild(0).Content(sourceCode)
+		}
+	}
+	if label != "" {
+		label = prefix + label
+	}
+	return label, t
+}
+
+// elixirSignature uses the parameter names of a def/defp as inputs, or
+// the types from the @spec of the function (with the same arity) among
+// the module attributes right before it.
+func elixirSignature(n *sitter.Node, sourceCode []byte, f *Func) {
+	f.Args, f.Rets = []string{}, []string{"_"}
+
+	head := elixirCallArgs(n).NamedChild(0)
+	if head.Type() == "binary_operator" {
+		head = head.ChildByFieldName("left")
+	}
+	if args := elixirCallArgs(head); args != nil {
+		for i := 0; i < int(args.NamedChildCount()); i++ {
+			a := args.NamedChild(i)
+			if a.Type() == "binary_operator" && a.ChildByFieldName("operator").Type() == "\\\\" {
+				a = a.ChildByFieldName("left") // default argument
+			}
+			f.Args = append(f.Args, a.Content(sourceCode))
+		}
+	}
+
+	for attr := n.PrevNamedSibling(); attr != nil && attr.Type() == "unary_operator"; attr = attr.PrevNamedSibling() {
+		spec := attr.ChildByFieldName("operand")
+		if spec == nil || !isElixirCall(spec, "spec", sourceCode) {
+			continue
+		}
+
+		sig := elixirCallArgs(spec)
+		if sig == nil || sig.NamedChild(0).Type() != "binary_operator" {
+			continue
+		}
+
+		sig = sig.NamedChild(0)
+		call := sig.ChildByFieldName("left")
+		if !isElixirCall(call, f.Name, sourceCode) {
+			continue
+		}
+
+		types := []string{}
+		if args := elixirCallArgs(call); args != nil {
+			for i := 0; i < int(args.NamedChildCount()); i++ {
+				types = append(types, args.NamedChild(i).Content(sourceCode))
+			}
+		}
+		if len(types) != len(f.Args) {
+			continue
+		}
+
+		f.Args = types
+		f.Rets = []string{sig.ChildByFieldName("right").Content(sourceCode)}
+		return
+	}
+}
+
+func isElixirCall(n *sitter.Node, name string, sourceCode []byte) bool {
+	if n == nil {
+		return false
+	}
+	if n.Type() == "identifier" {
+		return n.Content(sourceCode) == name
+	}
+	target := n.ChildByFieldName("target")
+	return n.Type() == "call" && target != nil && target.Content(sourceCode) == name
+}
+
+// elixirCallArgs returns the arguments node of a call (nil for calls
+// without parentheses or a bare identifier)
+func elixirCallArgs(n *sitter.Node) *sitter.Node {
+	if n.Type() != "call" {
+		return nil
+	}
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		if c := n.NamedChild(i); c.Type() == "arguments" {
+			return c
+		}
+	}
+	return nil
+}
+
+// phpType renders parameters as their type hint, or `_` if they do not
+// have one, and variadic ones (`int ...$ids`) as `...int`
+func phpType(n *sitter.Node, sourceCode []byte) string {
+	switch n.Type() {
+	case "simple_parameter", "variadic_parameter", "property_promotion_parameter":
+		t := "_"
+		if hint := n.ChildByFieldName("type"); hint != nil {
+			t = hint.Content(sourceCode)
+		}
+		if n.Type() == "variadic_parameter" {
+			t = "..." + t
+		}
+		return t
+	}
+	return n.Content(sourceCode)
+}
+
+var (
+	luaParam  = regexp.MustCompile(`^---\s*@param\s+([\w.]+)\??\s+(\S+)`)
+	luaReturn = regexp.MustCompile(`^---\s*@return\s+(\S+)`)
+)
+
+// protoSignature uses the request and response messages of an rpc
+// (prefixed with `stream` for streams) as its input and output
+func protoSignature(n *sitter.Node, sourceCode []byte, f *Func) {
+	types := []string{}
+	stream := false
+	for i := 0; i < int(n.ChildCount()); i++ {
+		c := n.Child(i)
+		switch c.Type() {
+		case "stream":
+			stream = true
+		case "message_or_enum_type":
+			t := c.Content(sourceCode)
+			if stream {
+				t = "stream " + t
+			}
+			types = append(types, t)
+			stream = false
+		}
+	}
+
+	if len(types) == 2 {
+		f.Args, f.Rets = types[:1], types[1:]
+	}
+
+	if service := n.Parent(); service != nil && service.Type() == "service" {
+		for i := 0; i < int(service.NamedChildCount()); i++ {
+			if c := service.NamedChild(i); c.Type() == "service_name" {
+				f.Receiver = c.Content(sourceCode)
+			}
+		}
+	}
+}
+
+// hclMetaArguments are arguments of (terraform) blocks that are not
+// passed on to the resource or module
+var hclMetaArguments = map[string]bool{
+	"count": true, "for_each": true, "depends_on": true, "provider": true,
+	"providers": true, "source": true, "version": true, "lifecycle": true,
+	"provisioner": true, "connection": true,
+}
+
+// hclSignature creates a pseudo signature for a terraform block using
+// its labels for the name, eg: `resource "aws_instance" "web"` is
+// `aws_instance.web ( ami, instance_type ) -> ( aws_instance )`
+func hclSignature(n *sitter.Node, sourceCode []byte, f *Func) {
+	kind := f.Name
+	labels := []string{}
+	args := map[string]*sitter.Node{}
+	f.Args, f.Rets = []string{}, []string{}
+
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		c := n.NamedChild(i)
+		switch c.Type() {
+		case "string_lit":
+			labels = append(labels, strings.Trim(c.Content(sourceCode), `"`))
+		case "body":
+			for j := 0; j < int(c.NamedChildCount()); j++ {
+				a := c.NamedChild(j)
+				if a.Type() != "attribute" && a.Type() != "block" {
+					continue
+				}
+
+				name := a.NamedChild(0).Content(sourceCode)
+				if a.Type() == "attribute" {
+					args[name] = a.NamedChild(1)
+				}
+				if !hclMetaArguments[name] {
+					f.Args = append(f.Args, name)
+				}
+			}
+		}
+	}
+
+	value := func(name string) string {
+		if v, ok := args[name]; ok {
+			return strings.Trim(strings.Join(strings.Fields(v.Content(sourceCode)), " "), `"`)
+		}
+		return "_"
+	}
+
+	switch {
+	case kind == "resource" && len(labels) == 2:
+		f.Name = labels[0] + "." + labels[1]
+		f.Rets = []string{labels[0]}
+	case kind == "data" && len(labels) == 2:
+		f.Name = "data." + labels[0] + "." + labels[1]
+		f.Rets = []string{labels[0]}
+	case kind == "module" && len(labels) == 1:
+		f.Name = "module." + labels[0]
+		f.Rets = []string{value("source")}
+	case kind == "variable" && len(labels) == 1:
+		f.Name = "var." + labels[0]
+		f.Args, f.Rets = []string{}, []string{value("type")}
+	case kind == "output" && len(labels) == 1:
+		f.Name = "output." + labels[0]
+		f.Args, f.Rets = []string{}, []string{value("value")}
+	}
+}
+
+// luaSignature uses the parameter names as inputs, replacing them with
+// types from `---@param` annotations in the comment block right above
+// the function. Outputs come from `---@return` and are `_` otherwise.
+func luaSignature(n *sitter.Node, sourceCode []byte, f *Func) {
+	f.Name = strings.TrimSpace(f.Name)
+	f.Args, f.Rets = []string{}, []string{}
+
+	// nodes of this grammar include the whitespace before them and
+	// functions the annotations above them, so the function starts at
+	// the `function` or `local` keyword
+	start := int(n.StartByte())
+	for i := 0; i < int(n.ChildCount()); i++ {
+		if c := n.Child(i); c.Type() != "emmy_documentation" && c.Type() != "comment" {
+			start = int(c.StartByte())
+			break
+		}
+	}
+	for start < len(sourceCode) && strings.ContainsRune(" \t\r\n", rune(sourceCode[start])) {
+		start++
+	}
+	f.Loc = span(sourceCode, start, int(n.EndByte()))
+	f.BodyLoc = bodyRows(sourceCode, start, int(n.EndByte()))
+
+	fn := n
+	if v := n.ChildByFieldName("value"); v != nil {
+		fn = v
+	}
+	for i := 0; i < int(fn.NamedChildCount()); i++ {
+		if params := fn.NamedChild(i); params.Type() == "parameter_list" {
+			for j := 0; j < int(params.NamedChildCount()); j++ {
+				f.Args = append(f.Args, strings.TrimSpace(params.NamedChild(j).Content(sourceCode)))
+			}
+			break
+		}
+	}
+
+	// the grammar does not parse all the annotations, so they are
+	// read from the lines right above the function, walking back from
+	// the start of the line it is on
+	params, comment := map[string]string{}, []string{}
+	end := bytes.LastIndexByte(sourceCode[:start], '\n')
+	for end >= 0 {
+		lineStart := bytes.LastIndexByte(sourceCode[:end], '\n') + 1
+		line := strings.TrimSpace(string(sourceCode[lineStart:end]))
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		comment = append([]string{string(sourceCode[lineStart:end])}, comment...)
+		end = lineStart - 1
+
+		if m := luaParam.FindStringSubmatch(line); m != nil {
+			params[m[1]] = m[2]
+		} else if m := luaReturn.FindStringSubmatch(line); m != nil {
+			f.Rets = append([]string{m[1]}, f.Rets...)
+		}
+	}
+
+	for i, a := range f.Args {
+		if t, ok := params[a]; ok {
+			f.Args[i] = t
+		}
+	}
+	if len(f.Rets) == 0 {
+		f.Rets = []string{"_"}
+	}
+	f.Doc = cleanComment(strings.Join(comment, "\n"))
+}
@@ -0,0 +1,550 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// parsedFunc is the part of a Func that the parse tests compare
+type parsedFunc struct {
+	Name string
+	Args []string
+	Rets []string
+}
+
+type parseTest struct {
+	name   string
+	source string
+	want   []parsedFunc
+}
+
+// testParse checks the functions that getFuncs finds in the sources of
+// the tests for language, when parsed as a file at path
+func testParse(t *testing.T, language, path string, tests []parseTest) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			funcs, err := getFuncs([]byte(tt.source), file{Language: language, Path: path})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []parsedFunc{}
+			for _, f := range funcs {
+				got = append(got, parsedFunc{f.Name, f.Args, f.Rets})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getFuncs(%q) = %v, want %v", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTypeScript(t *testing.T) {
+	testParse(t, "typescript", "a.ts", []parseTest{
+		{"rest parameter", "function join(sep: string, ...xs: string[]): string { return '' }",
+			[]parsedFunc{{Name: "join", Args: []string{"string", "...string"}, Rets: []string{"string"}}}},
+		{"rest array", "function all(...xs: Array<number>) {}",
+			[]parsedFunc{{Name: "all", Args: []string{"...number"}, Rets: []string{}}}},
+		{"readonly rest", "function any(...xs: readonly boolean[]) {}",
+			[]parsedFunc{{Name: "any", Args: []string{"...boolean"}, Rets: []string{}}}},
+		{"array parameter", "function sum(xs: number[]): number { return 0 }",
+			[]parsedFunc{{Name: "sum", Args: []string{"number[]"}, Rets: []string{"number"}}}},
+	})
+}
+
+func TestParseJavaScript(t *testing.T) {
+	testParse(t, "javascript", "a.js", []parseTest{
+		{"rest parameter", "function log(level, ...args) {}",
+			[]parsedFunc{{Name: "log", Args: []string{"level", "...args"}, Rets: []string{"_"}}}},
+	})
+}
+
+func TestSearchTypeScriptRest(t *testing.T) {
+	source := "function g(xs: string[]) {}\nfunction f(...xs: string[]) {}\n"
+	funcs, err := getFuncs([]byte(source), file{Language: "typescript", Path: "a.ts"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fwd, err := search(funcs, "( string, string ) -> ( )", "default", false, searchOptions{Weights: defaultWeights, Quiet: true}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fwd) == 0 || fwd[0].Func.Name != "f" || fwd[0].Distance != 0 {
+		t.Errorf("search found %v, want f first with a distance of 0", fwd)
+	}
+}
+
+func TestParseLua(t *testing.T) {
+	testParse(t, "lua", "a.lua", []parseTest{
+		{"annotations", "---@param a number\n---@param b number\n---@return number\nfunction add(a, b)\n  return a + b\nend\n",
+			[]parsedFunc{{Name: "add", Args: []string{"number", "number"}, Rets: []string{"number"}}}},
+		{"only the comment right above", "---@return string\nlocal x = 1\n\n---@param s string\n---@return string\n---@return boolean\nlocal function trim(s)\n  return s\nend\nfunction first(n) end\n",
+			[]parsedFunc{
+				{Name: "trim", Args: []string{"string"}, Rets: []string{"string", "boolean"}},
+				{Name: "first", Args: []string{"n"}, Rets: []string{"_"}},
+			}},
+		{"first line", "function noop() end",
+			[]parsedFunc{{Name: "noop", Args: []string{}, Rets: []string{"_"}}}},
+	})
+}
+
+func TestParseHaskell(t *testing.T) {
+	testParse(t, "haskell", "a.hs", []parseTest{
+		{"constraints", "sortOn :: Ord b => (a -> b) -> [a] -> [a]\nsortOn f = id\n",
+			[]parsedFunc{{Name: "sortOn", Args: []string{"(a -> b)", "[a]"}, Rets: []string{"[a]"}}}},
+		{"several names", "foo, bar :: Int -> Int\nfoo x = x\nbar x = x\n",
+			[]parsedFunc{
+				{Name: "foo", Args: []string{"Int"}, Rets: []string{"Int"}},
+				{Name: "bar", Args: []string{"Int"}, Rets: []string{"Int"}},
+			}},
+		{"multiline", "lookupAll\n  :: String\n  -> Map String Int -- the index\n  -> Maybe Int\nlookupAll = undefined\n",
+			[]parsedFunc{{Name: "lookupAll", Args: []string{"String", "Map String Int"}, Rets: []string{"Maybe Int"}}}},
+		{"operator", "(<+>) :: a -> a -> a\n",
+			[]parsedFunc{{Name: "(<+>)", Args: []string{"a", "a"}, Rets: []string{"a"}}}},
+		{"value", "answer :: Int\nanswer = 42\n",
+			[]parsedFunc{{Name: "answer", Args: []string{}, Rets: []string{"Int"}}}},
+	})
+}
+
+func TestParseOCaml(t *testing.T) {
+	testParse(t, "ocaml", "a.ml", []parseTest{
+		{"annotated", "let add (a : int) (b : int) : int = a + b\n",
+			[]parsedFunc{{Name: "add", Args: []string{"int", "int"}, Rets: []string{"int"}}}},
+		{"without annotations", "let rec length = function [] -> 0 | _ :: xs -> 1 + length xs\n",
+			[]parsedFunc{}},
+		{"in a module", "module M = struct\n  let name (u : user) : string = u.name\nend\n",
+			[]parsedFunc{{Name: "name", Args: []string{"user"}, Rets: []string{"string"}}}},
+		{"unit", "let main () : unit = ()\n",
+			[]parsedFunc{{Name: "main", Args: []string{"unit"}, Rets: []string{"unit"}}}},
+	})
+
+	// labels are kept (with a ? for optional ones) and default values
+	// are not types
+	source := "let find ~key ?(default = 0) ~(x : int) ?(y : int = 0) ~k:(k : string) ?z (tbl : t) = default\n"
+	funcs, err := getFuncs([]byte(source), file{Language: "ocaml", Path: "a.ml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantArgs := []string{"_", "_", "int", "int", "string", "_", "t"}
+	wantLabels := []string{"key", "?default", "x", "?y", "k", "?z", ""}
+	if len(funcs) != 1 || !reflect.DeepEqual(funcs[0].Args, wantArgs) || !reflect.DeepEqual(funcs[0].Labels, wantLabels) {
+		t.Errorf("getFuncs(%q) = %v, want the arguments %q with the labels %q", source, funcs, wantArgs, wantLabels)
+	}
+}
+
+func TestParseOCamlInterface(t *testing.T) {
+	testParse(t, "ocaml_interface", "a.mli", []parseTest{
+		{"value", "val add : int -> int -> int\n",
+			[]parsedFunc{{Name: "add", Args: []string{"int", "int"}, Rets: []string{"int"}}}},
+		{"type constructor", "val parse : string -> (t, string) result\n",
+			[]parsedFunc{{Name: "parse", Args: []string{"string"}, Rets: []string{"(t, string) result"}}}},
+		{"external", "external length : string -> int = \"%string_length\"\n",
+			[]parsedFunc{{Name: "length", Args: []string{"string"}, Rets: []string{"int"}}}},
+		{"constant", "type t\nval empty : t\n",
+			[]parsedFunc{{Name: "empty", Args: []string{}, Rets: []string{"t"}}}},
+	})
+
+	// the locations do not include the module type the file is parsed in
+	funcs, err := getFuncs([]byte("val add : int -> int -> int\nval neg : int -> int\n"), file{Language: "ocaml_interface", Path: "a.mli"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range [][]int{{0, 0, 0}, {1, 0, 28}} {
+		if len(funcs) <= i || !reflect.DeepEqual(funcs[i].Loc[:3], want) {
+			t.Fatalf("getFuncs found functions at %v, want %v", funcs, want)
+		}
+	}
+}
+
+func TestParseDart(t *testing.T) {
+	testParse(t, "dart", "a.dart", []parseTest{
+		{"arrow function", "int add(int a, int b) => a + b;\n",
+			[]parsedFunc{{Name: "add", Args: []string{"int", "int"}, Rets: []string{"int"}}}},
+		{"async with named parameters", "Future<List<String>> fetch(String url, {int retries = 3}) async {\n  return [];\n}\n",
+			[]parsedFunc{{Name: "fetch", Args: []string{"String", "int"}, Rets: []string{"Future<List<String>>"}}}},
+		{"methods", "class Point {\n  @override\n  double distanceTo(Point other) { return 0; }\n  void log(String msg, [int level = 0]) {}\n}\n",
+			[]parsedFunc{
+				{Name: "distanceTo", Args: []string{"Point"}, Rets: []string{"double"}},
+				{Name: "log", Args: []string{"String", "int"}, Rets: []string{"void"}},
+			}},
+		{"untyped", "main(args) {\n  if (args.isEmpty) { print('x'); }\n}\n",
+			[]parsedFunc{{Name: "main", Args: []string{"_"}, Rets: []string{"_"}}}},
+		{"abstract method", "abstract class Shape {\n  double area();\n}\n",
+			[]parsedFunc{{Name: "area", Args: []string{}, Rets: []string{"double"}}}},
+	})
+}
+
+func TestParseErlang(t *testing.T) {
+	testParse(t, "erlang", "a.erl", []parseTest{
+		{"spec", "-module(a).\n\n-spec add(integer(), integer()) -> integer().\nadd(A, B) -> A + B.\n",
+			[]parsedFunc{{Name: "add", Args: []string{"integer()", "integer()"}, Rets: []string{"integer()"}}}},
+		{"union result", "-spec parse(binary()) -> {ok, map()} | {error, term()}.\nparse(B) -> {ok, #{}}.\n",
+			[]parsedFunc{{Name: "parse", Args: []string{"binary()"}, Rets: []string{"{ok, map()} | {error, term()}"}}}},
+		{"constraints", "-spec get(Key, [T]) -> T when Key :: atom().\nget(K, L) -> hd(L).\n",
+			[]parsedFunc{{Name: "get", Args: []string{"atom()", "[T]"}, Rets: []string{"T"}}}},
+		{"several clauses", "-spec size(list()) -> integer(); (map()) -> integer().\nsize(L) when is_list(L) -> length(L);\nsize(M) -> maps:size(M).\n",
+			[]parsedFunc{
+				{Name: "size", Args: []string{"list()"}, Rets: []string{"integer()"}},
+				{Name: "size", Args: []string{"map()"}, Rets: []string{"integer()"}},
+			}},
+		{"without a spec", "first(X, Y) -> X.\nfirst(X, Y, _) -> X.\n",
+			[]parsedFunc{
+				{Name: "first", Args: []string{"X", "Y"}, Rets: []string{"_"}},
+				{Name: "first", Args: []string{"X", "Y", "_"}, Rets: []string{"_"}},
+			}},
+		{"callback", "-callback init(Args :: term()) -> {ok, State :: term()}.\n",
+			[]parsedFunc{{Name: "init", Args: []string{"term()"}, Rets: []string{"{ok, term()}"}}}},
+		{"escript", "#!/usr/bin/env \"escript\n-spec main([string()]) -> ok.\nmain(Args) -> io:format(\"~p~n\", [$\"]).\n",
+			[]parsedFunc{{Name: "main", Args: []string{"[string()]"}, Rets: []string{"ok"}}}},
+	})
+}
+
+func TestParseJulia(t *testing.T) {
+	testParse(t, "julia", "a.jl", []parseTest{
+		{"function", "function add(a::Int, b::Int)::Int\n    return a + b\nend\n",
+			[]parsedFunc{{Name: "add", Args: []string{"Int", "Int"}, Rets: []string{"Int"}}}},
+		{"short form", "scale(v::Vector{Float64}, k::Real) = v .* k\n",
+			[]parsedFunc{{Name: "scale", Args: []string{"Vector{Float64}", "Real"}, Rets: []string{"_"}}}},
+		{"comparison is not a definition", "f(x) == 1 && println(x)\n",
+			[]parsedFunc{}},
+		{"keyword arguments", "function plot(xs::Vector; color::Symbol=:red)\nend\n",
+			[]parsedFunc{{Name: "plot", Args: []string{"Vector", "Symbol"}, Rets: []string{"_"}}}},
+		{"multiple dispatch", "area(s::Square) = s.side^2\narea(c::Circle) = pi * c.r^2\n",
+			[]parsedFunc{
+				{Name: "area", Args: []string{"Square"}, Rets: []string{"_"}},
+				{Name: "area", Args: []string{"Circle"}, Rets: []string{"_"}},
+			}},
+		{"untyped", "function untyped(x, y)\n    x\nend\n",
+			[]parsedFunc{{Name: "untyped", Args: []string{"_", "_"}, Rets: []string{"_"}}}},
+	})
+}
+
+func TestParseGraphQL(t *testing.T) {
+	testParse(t, "graphql", "a.graphql", []parseTest{
+		{"query", "type Query {\n  \"Finds a user\"\n  user(id: ID!): User\n  users(first: Int, after: String): [User!]!\n  me: User\n}\n",
+			[]parsedFunc{
+				{Name: "user", Args: []string{"ID!"}, Rets: []string{"User"}},
+				{Name: "users", Args: []string{"Int", "String"}, Rets: []string{"[User!]!"}},
+				{Name: "me", Args: []string{}, Rets: []string{"User"}},
+			}},
+		{"only the fields with arguments of other types", "type User {\n  name: String\n  friends(first: Int = 10): [User] @deprecated\n}\n",
+			[]parsedFunc{{Name: "friends", Args: []string{"Int"}, Rets: []string{"[User]"}}}},
+		{"schema", "schema { query: Root }\ntype Query { a: Int }\nextend type Root {\n  # the version\n  version: String\n}\n",
+			[]parsedFunc{{Name: "version", Args: []string{}, Rets: []string{"String"}}}},
+	})
+}
+
+func TestParseSQL(t *testing.T) {
+	testParse(t, "sql", "a.sql", []parseTest{
+		{"function", "CREATE FUNCTION add(a integer, b integer) RETURNS integer AS $$\n  SELECT a + b;\n$$ LANGUAGE SQL;\n",
+			[]parsedFunc{{Name: "add", Args: []string{"integer", "integer"}, Rets: []string{"integer"}}}},
+		{"schema and defaults", "create or replace function public.find(p_name text, p_limit int default 10) returns setof users as $body$\nbegin\n  return query select 'create function x()';\nend;\n$body$ language plpgsql;\n",
+			[]parsedFunc{{Name: "public.find", Args: []string{"text", "int"}, Rets: []string{"setof users"}}}},
+		{"out parameters", "CREATE PROCEDURE split(IN s text, OUT head text, INOUT n int) LANGUAGE plpgsql AS $$ BEGIN END $$;\n",
+			[]parsedFunc{{Name: "split", Args: []string{"text", "int"}, Rets: []string{"text", "int"}}}},
+		{"returns table", "CREATE FUNCTION stats() RETURNS TABLE (n int, avg numeric(10, 2)) AS $$ SELECT 1, 2 $$ LANGUAGE SQL;\n",
+			[]parsedFunc{{Name: "stats", Args: []string{}, Rets: []string{"TABLE (n int, avg numeric(10, 2))"}}}},
+		{"t-sql procedure", "CREATE PROCEDURE [dbo].[GetUser] @id INT, @name NVARCHAR(50) = NULL OUTPUT\nAS\nBEGIN\n  SELECT 1\nEND\nGO\n",
+			[]parsedFunc{{Name: "dbo.GetUser", Args: []string{"INT", "NVARCHAR(50)"}, Rets: []string{"NVARCHAR(50)"}}}},
+	})
+}
+
+func TestParseNim(t *testing.T) {
+	testParse(t, "nim", "a.nim", []parseTest{
+		{"shared type", "proc add(a, b: int): int =\n  ## Adds them\n  a + b\n",
+			[]parsedFunc{{Name: "add", Args: []string{"int", "int"}, Rets: []string{"int"}}}},
+		{"exported", "func name*(p: Person): string = p.name\n",
+			[]parsedFunc{{Name: "name", Args: []string{"Person"}, Rets: []string{"string"}}}},
+		{"no result", "proc log(msg: string) =\n  echo msg\n",
+			[]parsedFunc{{Name: "log", Args: []string{"string"}, Rets: []string{"_"}}}},
+		{"generics", "iterator items[T](s: seq[T]): T =\n  discard\n",
+			[]parsedFunc{{Name: "items", Args: []string{"seq[T]"}, Rets: []string{"T"}}}},
+		{"defaults and pragmas", "proc retry(n = 3; cb: proc () {.closure.}): bool {.raises: [].} =\n  true\n",
+			[]parsedFunc{{Name: "retry", Args: []string{"_", "proc ()"}, Rets: []string{"bool"}}}},
+		{"operator", "proc `$`*(p: Point): string\n",
+			[]parsedFunc{{Name: "$", Args: []string{"Point"}, Rets: []string{"string"}}}},
+	})
+}
+
+func TestParseKotlin(t *testing.T) {
+	testParse(t, "kotlin", "a.kt", []parseTest{
+		{"vararg", "fun log(level: Int, vararg msgs: String): Unit {}\n",
+			[]parsedFunc{{Name: "log", Args: []string{"Int", "...String"}, Rets: []string{"Unit"}}}},
+		{"vararg first", "fun <T> listOf(vararg elements: T, tag: String?): List<T> = TODO()\n",
+			[]parsedFunc{{Name: "listOf", Args: []string{"...T", "String?"}, Rets: []string{"List<T>"}}}},
+		{"methods", "class Store<K> {\n    fun find(key: K, default: String? = null): List<String> = listOf()\n    suspend fun load(): Unit {}\n}\n",
+			[]parsedFunc{
+				{Name: "find", Args: []string{"K", "String?"}, Rets: []string{"List<String>"}},
+				{Name: "load", Args: []string{}, Rets: []string{"Unit"}},
+			}},
+		{"bounded type parameter", "fun <T : Comparable<T>> max(a: T, b: T): T = if (a > b) a else b\n",
+			[]parsedFunc{{Name: "max", Args: []string{"T", "T"}, Rets: []string{"T"}}}},
+	})
+
+	// extension functions are methods of the type they extend
+	funcs, err := getFuncs([]byte("fun String.words(limit: Int): List<String> = split(\" \")\n"), file{Language: "kotlin", Path: "a.kt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(funcs) != 1 || funcs[0].Receiver != "String" || !reflect.DeepEqual(funcs[0].Args, []string{"Int"}) {
+		t.Errorf("getFuncs found %v, want words with the receiver String and an Int argument", funcs)
+	}
+}
+
+func TestParseCSharp(t *testing.T) {
+	testParse(t, "csharp", "a.cs", []parseTest{
+		{"modifiers", `class A {
+    bool TryParse(string s, out int value, in Span<byte> buf) { value = 0; return true; }
+    void Swap<T>(ref T a, ref T b) {}
+}
+`, []parsedFunc{
+			{Name: "TryParse", Args: []string{"string", "out int", "Span<byte>"}, Rets: []string{"bool"}},
+			{Name: "Swap", Args: []string{"ref T", "ref T"}, Rets: []string{"void"}},
+		}},
+		{"nullable", `static class A {
+    static Nullable<int> Find(this int[] xs, int? start) => null;
+}
+`, []parsedFunc{{Name: "Find", Args: []string{"int[]", "int?"}, Rets: []string{"int?"}}}},
+		{"array return", `class A { int[] Range(int n) { return null; } }
+`, []parsedFunc{{Name: "Range", Args: []string{"int"}, Rets: []string{"int[]"}}}},
+		{"params", `class A { void Log(int level, params string[] msgs) {} }
+`, []parsedFunc{{Name: "Log", Args: []string{"int", "...string"}, Rets: []string{"void"}}}},
+		{"constructors and properties", `public class Store {
+    public Store(IDictionary<string, int> items) {}
+    public int Count { get; }
+    public string Name => "x";
+    public int Size { set { } }
+}
+`, []parsedFunc{
+			{Name: "Store", Args: []string{"IDictionary<string, int>"}, Rets: []string{"Store"}},
+			{Name: "Count", Args: []string{}, Rets: []string{"int"}},
+			{Name: "Name", Args: []string{}, Rets: []string{"string"}},
+		}},
+	})
+}
+
+func TestParseJava(t *testing.T) {
+	testParse(t, "java", "A.java", []parseTest{
+		{"methods", `class Store<K> {
+    public Optional<String> find(K key, int... ids) { return Optional.empty(); }
+    public Store(Map<K, String> items) {}
+    static <T extends Comparable<T>> List<T> sorted(Collection<? extends T> xs) throws IOException { return null; }
+    void close() {}
+}
+`, []parsedFunc{
+			{Name: "find", Args: []string{"K", "...int"}, Rets: []string{"Optional<String>"}},
+			{Name: "Store", Args: []string{"Map<K, String>"}, Rets: []string{"Store"}},
+			{Name: "sorted", Args: []string{"Collection<? extends T>"}, Rets: []string{"List<T>"}},
+			{Name: "close", Args: []string{}, Rets: []string{"void"}},
+		}},
+		{"interface", "interface Reader { int read(byte[] buf, int off); }\n",
+			[]parsedFunc{{Name: "read", Args: []string{"byte[]", "int"}, Rets: []string{"int"}}}},
+	})
+
+	// type parameters of generic methods can be bound by queries
+	funcs, err := getFuncs([]byte("class A { static <T> T first(List<T> xs) { return null; } }\n"), file{Language: "java", Path: "A.java"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(funcs) != 1 || !reflect.DeepEqual(funcs[0].TypeParams, []string{"T"}) {
+		t.Errorf("getFuncs found %v, want first with the type parameter T", funcs)
+	}
+}
+
+func TestParseC(t *testing.T) {
+	testParse(t, "c", "a.c", []parseTest{
+		{"pointers", "static const char *name(const struct user *u) { return u->name; }\n",
+			[]parsedFunc{{Name: "name", Args: []string{"const struct user *"}, Rets: []string{"const char *"}}}},
+		{"prototype with varargs", "int logf(const char *fmt, ...);\n",
+			[]parsedFunc{{Name: "logf", Args: []string{"const char *", "..."}, Rets: []string{"int"}}}},
+		{"qualified types", "unsigned long long count(int n, char **argv, size_t len) { return 0; }\n",
+			[]parsedFunc{{Name: "count", Args: []string{"int", "char **", "size_t"}, Rets: []string{"unsigned long long"}}}},
+		{"void parameters", "void reset(void) {}\n",
+			[]parsedFunc{{Name: "reset", Args: []string{}, Rets: []string{"void"}}}},
+		{"K&R", "int old(a, b) int a; char *b; { return 0; }\n",
+			[]parsedFunc{{Name: "old", Args: []string{"a", "b"}, Rets: []string{"int"}}}},
+	})
+}
+
+func TestParseCPP(t *testing.T) {
+	testParse(t, "cpp", "a.cpp", []parseTest{
+		{"class members", `class Buffer {
+public:
+    Buffer(std::size_t n);
+    const std::string &str() const { return s; }
+    template <typename T> std::vector<T> take(const std::vector<T> &xs, int n = 1);
+    virtual ~Buffer();
+};
+`, []parsedFunc{
+			{Name: "Buffer", Args: []string{"std::size_t"}, Rets: []string{}},
+			{Name: "str", Args: []string{}, Rets: []string{"const std::string &"}},
+			{Name: "take", Args: []string{"const std::vector<T> &", "int"}, Rets: []string{"std::vector<T>"}},
+			{Name: "~Buffer", Args: []string{}, Rets: []string{}},
+		}},
+		{"trailing return type", "auto ns::Buffer::size() const -> std::size_t { return 0; }\n",
+			[]parsedFunc{{Name: "ns::Buffer::size", Args: []string{}, Rets: []string{"std::size_t"}}}},
+	})
+}
+
+func TestParseRuby(t *testing.T) {
+	testParse(t, "ruby", "a.rb", []parseTest{
+		{"untyped", "class Store\n  def fetch(key, default = nil)\n    []\n  end\n\n  def self.open(path, mode: \"r\", &block)\n  end\n\n  def each(*items, **opts); end\nend\n",
+			[]parsedFunc{
+				{Name: "fetch", Args: []string{"key", "default"}, Rets: []string{"_"}},
+				{Name: "open", Args: []string{"path", "mode", "&block"}, Rets: []string{"_"}},
+				{Name: "each", Args: []string{"*items", "**opts"}, Rets: []string{"_"}},
+			}},
+		{"sorbet", `class Store
+  sig { params(key: String, default: T.nilable(Integer)).returns(T::Array[String]) }
+  def fetch(key, default = nil)
+    []
+  end
+
+  sig { params(items: String).void }
+  def each(*items); end
+end
+`, []parsedFunc{
+			{Name: "fetch", Args: []string{"String", "T.nilable(Integer)"}, Rets: []string{"T::Array[String]"}},
+			{Name: "each", Args: []string{"String"}, Rets: []string{}},
+		}},
+	})
+}
+
+func TestParseSwift(t *testing.T) {
+	testParse(t, "swift", "a.swift", []parseTest{
+		{"struct", `struct Store {
+    func find(_ key: String, default value: Int? = nil) -> [String] { [] }
+    mutating func add(item: String, at index: Int) throws {}
+    init(items: [String: Int]) {}
+    static func make<T: Hashable>(from xs: T...) -> Set<T> { [] }
+}
+`, []parsedFunc{
+			{Name: "find", Args: []string{"String", "Int?"}, Rets: []string{"[String]"}},
+			{Name: "add", Args: []string{"String", "Int"}, Rets: []string{"throws"}},
+			{Name: "init", Args: []string{"[String: Int]"}, Rets: []string{"Store"}},
+			{Name: "make", Args: []string{"T..."}, Rets: []string{"Set<T>"}},
+		}},
+		{"protocol", "protocol Shape {\n    func area() -> Double\n}\n",
+			[]parsedFunc{{Name: "area", Args: []string{}, Rets: []string{"Double"}}}},
+	})
+
+	// labels are kept for labelled queries and functions in extensions
+	// are methods of the extended type
+	funcs, err := getFuncs([]byte("extension Array {\n    func chunked(into size: Int) -> [[Element]] { [] }\n}\n"), file{Language: "swift", Path: "a.swift"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(funcs) != 1 || funcs[0].Receiver != "Array" || !reflect.DeepEqual(funcs[0].Labels, []string{"into"}) {
+		t.Errorf("getFuncs found %v, want chunked with the receiver Array and the label into", funcs)
+	}
+}
+
+func TestParseScala(t *testing.T) {
+	testParse(t, "scala", "a.scala", []parseTest{
+		{"default value", "object Store {\n  def find(key: String, default: Option[Int] = None): List[String] = Nil\n}\n",
+			[]parsedFunc{{Name: "find", Args: []string{"String", "Option[Int]"}, Rets: []string{"List[String]"}}}},
+		{"curried and implicit", "object A {\n  def fold[A, B](xs: Seq[A])(z: B)(f: (B, A) => B)(implicit ord: Ordering[A]): B = z\n}\n",
+			[]parsedFunc{{Name: "fold", Args: []string{"Seq[A]", "B", "(B, A) => B", "Ordering[A]"}, Rets: []string{"B"}}}},
+		{"repeated", "object A {\n  def log(msgs: String*): Unit = ()\n}\n",
+			[]parsedFunc{{Name: "log", Args: []string{"String*"}, Rets: []string{"Unit"}}}},
+		{"abstract", "trait Shape { def area: Double }\n",
+			[]parsedFunc{{Name: "area", Args: []string{}, Rets: []string{"Double"}}}},
+	})
+}
+
+func TestParseZig(t *testing.T) {
+	testParse(t, "zig", "a.zig", []parseTest{
+		{"function", "pub fn add(a: i32, b: i32) i32 {\n    return a + b;\n}\n",
+			[]parsedFunc{{Name: "add", Args: []string{"i32", "i32"}, Rets: []string{"i32"}}}},
+		{"error union", "fn parse(allocator: std.mem.Allocator, input: []const u8) !Node {\n    return error.Bad;\n}\n",
+			[]parsedFunc{{Name: "parse", Args: []string{"std.mem.Allocator", "[]const u8"}, Rets: []string{"!Node"}}}},
+		{"comptime and optional", "pub fn max(comptime T: type, xs: []const T) ?T {\n    return null;\n}\n",
+			[]parsedFunc{{Name: "max", Args: []string{"type", "[]const T"}, Rets: []string{"?T"}}}},
+		{"in a struct", "const Point = struct {\n    x: f32,\n    pub fn len(self: Point) f32 { return 0; }\n};\n",
+			[]parsedFunc{{Name: "len", Args: []string{"Point"}, Rets: []string{"f32"}}}},
+	})
+}
+
+func TestParseElixir(t *testing.T) {
+	testParse(t, "elixir", "a.ex", []parseTest{
+		{"spec", "defmodule Store do\n  @spec fetch(map(), String.t()) :: {:ok, term()} | :error\n  def fetch(store, key) do\n    Map.fetch(store, key)\n  end\nend\n",
+			[]parsedFunc{{Name: "fetch", Args: []string{"map()", "String.t()"}, Rets: []string{"{:ok, term()} | :error"}}}},
+		{"without a spec", "defmodule Store do\n  defp build(name, opts \\\\ []), do: {name, opts}\n  def size(%{items: items}), do: length(items)\nend\n",
+			[]parsedFunc{
+				{Name: "build", Args: []string{"name", "opts"}, Rets: []string{"_"}},
+				{Name: "size", Args: []string{"%{items: items}"}, Rets: []string{"_"}},
+			}},
+		{"spec of the same arity", `defmodule Store do
+  @spec fetch(map(), String.t()) :: {:ok, term()} | :error
+  def fetch(store, key), do: Map.fetch(store, key)
+
+  @spec fetch(map()) :: :error
+  def fetch(store) when is_map(store), do: :error
+  def fetch(_, _, _), do: :error
+end
+`, []parsedFunc{
+			{Name: "fetch", Args: []string{"map()", "String.t()"}, Rets: []string{"{:ok, term()} | :error"}},
+			{Name: "fetch", Args: []string{"map()"}, Rets: []string{":error"}},
+			{Name: "fetch", Args: []string{"_", "_", "_"}, Rets: []string{"_"}},
+		}},
+	})
+}
+
+func TestParsePHP(t *testing.T) {
+	testParse(t, "php", "a.php", []parseTest{
+		{"function", "<?php\nfunction add(int $a, int $b): int { return $a + $b; }\n",
+			[]parsedFunc{{Name: "add", Args: []string{"int", "int"}, Rets: []string{"int"}}}},
+		{"methods", `<?php
+class Store {
+    public function __construct(private array $items = []) {}
+    public function find(string $key, ?int $default = null): ?string { return null; }
+    public static function log(string ...$msgs): void {}
+    function untyped($x) { return $x; }
+}
+`, []parsedFunc{
+			{Name: "__construct", Args: []string{"array"}, Rets: []string{}},
+			{Name: "find", Args: []string{"string", "?int"}, Rets: []string{"?string"}},
+			{Name: "log", Args: []string{"...string"}, Rets: []string{"void"}},
+			{Name: "untyped", Args: []string{"_"}, Rets: []string{}},
+		}},
+	})
+}
+
+func TestParseProtobuf(t *testing.T) {
+	source := "syntax = \"proto3\";\n\nservice Store {\n  rpc Get(GetRequest) returns (Item);\n  rpc Watch(stream WatchRequest) returns (stream Event) {}\n}\n"
+	testParse(t, "protobuf", "a.proto", []parseTest{
+		{"rpc", source, []parsedFunc{
+			{Name: "Get", Args: []string{"GetRequest"}, Rets: []string{"Item"}},
+			{Name: "Watch", Args: []string{"stream WatchRequest"}, Rets: []string{"stream Event"}},
+		}},
+	})
+
+	// methods are defined on their service
+	funcs, err := getFuncs([]byte(source), file{Language: "protobuf", Path: "a.proto"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range funcs {
+		if f.Receiver != "Store" {
+			t.Errorf("getFuncs found %s on %q, want it on Store", f.Name, f.Receiver)
+		}
+	}
+}
+
+func TestParseHCL(t *testing.T) {
+	testParse(t, "hcl", "main.tf", []parseTest{
+		{"resource", "resource \"aws_s3_bucket\" \"logs\" {\n  bucket = \"x\"\n  count  = 2\n  tags { team = \"a\" }\n}\n",
+			[]parsedFunc{{Name: "aws_s3_bucket.logs", Args: []string{"bucket", "tags"}, Rets: []string{"aws_s3_bucket"}}}},
+		{"data", "data \"aws_ami\" \"ubuntu\" {\n  most_recent = true\n}\n",
+			[]parsedFunc{{Name: "data.aws_ami.ubuntu", Args: []string{"most_recent"}, Rets: []string{"aws_ami"}}}},
+		{"module", "module \"vpc\" {\n  source = \"./vpc\"\n  cidr   = \"10.0.0.0/16\"\n}\n",
+			[]parsedFunc{{Name: "module.vpc", Args: []string{"cidr"}, Rets: []string{"./vpc"}}}},
+		{"variable and output", "variable \"region\" {\n  type = string\n}\n\noutput \"id\" {\n  value = aws_s3_bucket.logs.id\n}\n",
+			[]parsedFunc{
+				{Name: "var.region", Args: []string{}, Rets: []string{"string"}},
+				{Name: "output.id", Args: []string{}, Rets: []string{"aws_s3_bucket.logs.id"}},
+			}},
+	})
+}
@@ -1,20 +1,25 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
 	"sort"
-	"strconv"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
-	"github.com/agnivade/levenshtein"
 	sitter "github.com/smacker/go-tree-sitter"
-	"github.com/smacker/go-tree-sitter/golang"
 )
 
 const LINE_CLEAR = "\033[2K"
@@ -22,108 +27,670 @@ const LINE_CLEAR = "\033[2K"
 type file struct {
 	Language string
 	Path     string
+	Test     bool
+}
+
+// stringList is the value of a flag that can be repeated
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ", ") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// exitNoResults and exitError are the exit codes of searches without
+// results and of errors (including wrong usage), so that glee can be
+// used as a check in scripts like grep
+const (
+	exitNoResults = 1
+	exitError     = 2
+)
+
+// fatal logs the error and exits with exitError
+func fatal(v ...any) {
+	log.Print(v...)
+	os.Exit(exitError)
+}
+
+func fatalf(format string, v ...any) {
+	log.Printf(format, v...)
+	os.Exit(exitError)
 }
 
 func usage() {
 	name := filepath.Base(os.Args[0])
-	fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] <signature>\n", name)
+	fmt.Fprintf(os.Stderr, "Usage: %s [search] [OPTIONS] <signature>... [path...]\n", name)
+	fmt.Fprintf(os.Stderr, "       %s tags [OPTIONS] [path...]\n", name)
+	fmt.Fprintf(os.Stderr, "       %s stats [OPTIONS] [path...]\n", name)
+	fmt.Fprintf(os.Stderr, "       %s index [OPTIONS] [path...]\n", name)
+	fmt.Fprintf(os.Stderr, "       %s serve [OPTIONS] [path...]\n", name)
+	fmt.Fprintf(os.Stderr, "       %s completion bash|zsh|fish\n", name)
 	fmt.Println("Hoogle like search for functions in all languages") // TODO
 	fmt.Println("\nOptions:")
 	flag.PrintDefaults()
 	fmt.Printf("\nExample: %s -match includes '(Path, string) -> (Path, error)'\n", name)
+	fmt.Printf("         %s 'parse : ( []byte ) -> ( Config, error )'\n", name)
 }
 
 func main() {
-	match := flag.String("match", "default", "matching algorithm (options: includes, default)")
+	var expressions stringList
+	flag.Var(&expressions, "e", "query to search for, can be repeated to search for several at once (the arguments are then all paths)")
+	match := flag.String("match", "default", "matching algorithm (options: "+strings.Join(matchModes, ", ")+")")
+	outputFormat := flag.String("o", "text", "output format (options: "+strings.Join(outputNames(), ", ")+")")
+	format := flag.String("format", "", "Go template to print every result with instead of -o (eg: '{{.Path}}:{{.Line}} {{.Name}}{{.Signature}}'), see -o jsonl for the fields")
+	var print0 bool
+	flag.BoolVar(&print0, "0", false, "only print the paths of the files with results, each ending with a NUL (for xargs -0)")
+	flag.BoolVar(&print0, "print0", false, "same as -0")
+	sortBy := flag.String("sort", "score", "order to show the closest results in (options: score, path, name)")
+	groupBy := flag.String("group-by", "", "group the results by file, package or language")
+	showScore := flag.Bool("show-score", false, "show the distance of every result from the query")
+	explain := flag.Bool("explain", false, "show how much the name, args, rets and arity add to the distance of every result")
+	showDoc := flag.Bool("show-doc", false, "show the first line of the doc comment of the functions")
+	contextSize := flag.Int("C", 0, "show this many lines of source around the functions")
+	var showBody bodyLines
+	flag.Var(&showBody, "show-body", fmt.Sprintf("show the first lines of the body of the functions (%d by default, eg: -show-body=10)", defaultBodyLines))
+	hyperlinks := flag.String("hyperlinks", "auto", "make the locations in the text output clickable links (options: auto, always, never), auto uses them in terminals")
+	linkURL := flag.String("link-url", "", "Go template for the URL of the links instead of file:// ones (eg: 'https://github.com/meain/glee/blob/main/{{.Path}}#L{{.Line}}'), see -o jsonl for the fields")
+	colorMode := flag.String("color", "auto", "color the output and highlight the types in the query (options: auto, always, never), auto colors terminals unless NO_COLOR is set")
+	nullFile := flag.Bool("null-file", false, "end paths with a NUL instead of : with -o grep and -o emacs (for paths with a :, eg: from TRAMP)")
+	grammars := flag.String("grammars", defaultGrammarsDir(), "directory to load external grammars from")
+	langs := flag.String("lang", "", "comma separated list of languages to search (eg: go,typescript)")
+	notArg := flag.String("not-arg", "", "comma separated list of types the functions must not take (eg: context.Context)")
+	notRet := flag.String("not-ret", "", "comma separated list of types the functions must not return")
+	weightsFlag := flag.String("weights", "", "weights of the name, args, rets and arity differences when ranking (eg: rets=2,arity=1)")
+	metricName := flag.String("metric", "levenshtein", "distance metric to rank by (options: levenshtein, damerau, jaro-winkler, lcs)")
+	tfidf := flag.Bool("tfidf", false, "weigh types by how rare they are so that common ones (eg: error) matter less")
+	refineResults := flag.Bool("refine", false, "interactively narrow down the results with +type and -type after showing them")
+	popularity := flag.Bool("popularity", false, "rank functions that are referenced more often higher at the same distance")
+	wo := walkOptions{}
+	walkFlags(flag.CommandLine, &wo)
+	flag.BoolVar(&wo.AbsPaths, "abs-path", false, "show absolute paths instead of paths relative to the working directory")
+	filesFrom := flag.String("files-from", "", `search the files listed in this file (one per line), "-" for stdin, instead of a directory`)
+	listFiles := flag.Bool("list-files", false, "only print the files that would be searched in the paths (without a query), to see which ones are skipped")
+	verboseFlags(flag.CommandLine)
+	jobs := flag.Int("j", 0, "number of files to parse at once (0 for one per CPU)")
+	quiet := flag.Bool("q", false, "do not show the progress of parsing the files (only shown when stderr is a terminal)")
+	streamMode := flag.Bool("stream", false, "show the closest results so far on stderr while the files are parsed, before the sorted ones")
+	watchMode := flag.Bool("watch", false, "search again every time the files change, until interrupted")
+	noCache := flag.Bool("no-cache", false, "parse all the files again instead of using the functions cached from the previous searches")
+	preferExported := flag.Bool("prefer-exported", true, "rank exported Go functions above unexported ones at the same distance")
+	exactArity := flag.Bool("exact-arity", false, "only show functions with as many arguments and results as the query")
+	ignoreCase := flag.Bool("i", false, "match types ignoring case")
+	typos := flag.Int("typos", 1, "typos allowed in every name of a type with -match includes (eg: contex.Context), names shorter than 4 characters have to be exact")
+	looseWrappers := flag.Bool("loose-wrappers", false, "ignore pointers, slices and varargs (*, [] and ...) in types")
+	aliases := flag.String("aliases", defaultAliasFile(), "file with type aliases to use in queries (eg: ctx=context.Context)")
+	threshold := flag.Float64("threshold", 20, "maximum distance of the results to show (0 for exact matches only)")
+	limit := flag.Int("limit", 20, "maximum number of results to show")
+	all := flag.Bool("all", false, "show all the results (within -threshold if set)")
+	embedURL := flag.String("embed-url", "", "rank by similarity of embeddings from this OpenAI compatible endpoint, the query can be any text")
+	embedModel := flag.String("embed-model", "", "model to use with -embed-url")
+	normalize := flag.Bool("normalize", true, "treat builtin types of different languages as the same (eg: str, String and string)")
 	flag.Usage = usage
 
-	flag.Parse()
+	// `glee search` is the same as `glee`, the other subcommands have
+	// their own flags (but the completions are made from the ones above)
+	command, args := "search", os.Args[1:]
+	if len(args) > 0 && isSubcommand(args[0]) {
+		command, args = args[0], args[1:]
+	}
+	if command != "search" {
+		var err error
+		switch command {
+		case "tags":
+			err = tags(args)
+		case "stats":
+			err = stats(args)
+		case "index":
+			err = index(args)
+		case "serve":
+			err = serve(args)
+		case "completion":
+			err = completion(args, flag.CommandLine)
+		}
+		if err != nil {
+			fatal(err)
+		}
+		return
+	}
+
+	flag.CommandLine.Parse(queryArgs(flag.CommandLine, args))
 
-	args := flag.Args()
-	if len(args) < 1 {
-		flag.Usage()
-		os.Exit(1)
+	if err := loadEnv(flag.CommandLine); err != nil {
+		fatal(err)
+	}
+	if err := loadConfig(flag.CommandLine, defaultConfigFile(), projectConfigFile()); err != nil {
+		fatal(err)
 	}
 
-	uinput := args[0]
-	files := []file{}
-	root := "."
+	sortSet := false
+	flag.Visit(func(f *flag.Flag) { sortSet = sortSet || f.Name == "sort" })
 
-	if len(args) > 1 {
-		root = args[1]
+	if *all {
+		*limit = -1
+		thresholdSet := false
+		flag.Visit(func(f *flag.Flag) { thresholdSet = thresholdSet || f.Name == "threshold" })
+		if !thresholdSet {
+			*threshold = math.Inf(1)
+		}
 	}
 
-	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if err == nil {
-			if !info.IsDir() {
-				lang := getLanguage(info.Name())
-				if lang != "" {
-					files = append(files, file{Language: lang, Path: path})
-				}
-			}
+	color, err := useColor(*colorMode)
+	if err != nil {
+		fatal(err)
+	}
+
+	links, err := useHyperlinks(*hyperlinks)
+	if err != nil {
+		fatal(err)
+	}
+	var link *linker
+	if links {
+		if link, err = newLinker(*linkURL); err != nil {
+			fatal(err)
 		}
-		return nil
-	})
+	}
+
+	po := printOptions{Format: *outputFormat, Template: *format, Print0: print0, Threshold: *threshold, Limit: *limit, NullFile: *nullFile, Color: color, ShowDoc: *showDoc, ShowScore: *showScore, Explain: *explain, GroupBy: *groupBy, SortBy: *sortBy, Link: link, ShowBody: int(showBody), Context: *contextSize}
+	if _, err := getOutput(po, io.Discard); err != nil {
+		fatal(err)
+	}
+	if err := checkOrdering(po.GroupBy, po.SortBy); err != nil {
+		fatal(err)
+	}
+
+	if err := loadGrammars(*grammars); err != nil {
+		fatal(err)
+	}
+
+	w, err := parseWeights(*weightsFlag)
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
 
-	funcs := []Func{}
-	for _, f := range files {
-		fmt.Fprintf(os.Stderr, "%sProcessing %s\r", LINE_CLEAR, filepath.Base(f.Path))
+	m, err := getMetric(*metricName)
+	if err != nil {
+		fatal(err)
+	}
+	// the similarity of jaro-winkler does not depend on the cost of the types
+	if *tfidf && *metricName == "jaro-winkler" {
+		fatal("cannot use -tfidf with -metric jaro-winkler")
+	}
 
-		sourceCode, err := os.ReadFile(f.Path)
+	if err := loadQueryAliases(*aliases); err != nil {
+		fatal(err)
+	}
+
+	if *langs != "" {
+		if err := selectLanguages(strings.Split(*langs, ",")); err != nil {
+			fatal(err)
+		}
+	}
+
+	args = flag.Args()
+	if *listFiles {
+		found, err := printFiles(args, *filesFrom, wo, print0, os.Stdout)
 		if err != nil {
-			log.Fatal(err)
+			fatal(err)
+		}
+		if !found {
+			os.Exit(exitNoResults)
 		}
+		return
+	}
 
-		tf, err := getFuncs(sourceCode, f)
+	stdin := len(expressions) == 0 && (slices.Contains(args, "-") || (len(args) == 0 && !stdinIsTerminal()))
+	if i := slices.Index(args, "-"); stdin {
+		if *filesFrom == "-" {
+			if i >= 0 {
+				fatal("cannot read both the query and -files-from from stdin")
+			}
+		} else {
+			stdinQueries, err := readQueries(os.Stdin)
+			if err != nil {
+				fatal(err)
+			}
+			if i < 0 {
+				i = 0
+				args = []string{"-"}
+			}
+			args = slices.Replace(args, i, i+1, stdinQueries...)
+		}
+	}
+	// the query is asked for when there is none in a terminal
+	if len(args) == 0 && len(expressions) == 0 && stdinIsTerminal() && stderrIsTerminal() {
+		query, err := promptQuery(os.Stdin)
 		if err != nil {
-			log.Fatal(err)
+			fatal(err)
+		}
+		args = []string{query}
+	}
+	if len(args) < 1 && len(expressions) == 0 {
+		flag.Usage()
+		os.Exit(exitError)
+	}
+
+	if !slices.Contains(matchModes, *match) {
+		fmt.Printf("ERROR: Invalid match type '%s'\n", *match)
+		flag.Usage()
+		os.Exit(exitError)
+	}
+
+	// with -e all the arguments are paths
+	queries, roots := splitQueries(args, *match != "regex", *embedURL != "", *filesFrom == "")
+	if len(expressions) > 0 {
+		queries, _ = splitQueries(expressions, *match != "regex", *embedURL != "", false)
+		if roots = args; len(roots) == 0 {
+			roots = []string{"."}
+		}
+	}
+	if po.Color && *match != "regex" && *embedURL == "" {
+		po.Highlight = highlights(queries)
+	}
+	var files []file
+	var dirs []string // the directories to watch with -watch
+	if *filesFrom != "" {
+		files, err = readFileList(*filesFrom, wo)
+		for _, f := range files {
+			dirs = append(dirs, filepath.Dir(f.Path))
 		}
+	} else {
+		if *watchMode {
+			wo.Dirs = func(path string) { dirs = append(dirs, path) }
+		}
+		files, err = findAllFiles(roots, wo)
+		wo.Dirs = nil
+	}
+	if err != nil {
+		fatal(err)
+	}
 
-		funcs = append(funcs, tf...)
+	if *watchMode && *refineResults {
+		fatal("cannot use -watch with -refine")
 	}
+	if *streamMode && *embedURL != "" {
+		fatal("cannot use -stream with -embed-url")
+	}
+	cache := openCache(*noCache)
+
+	// runSearch parses the functions in files and prints the results (of
+	// every change with -watch)
+	runSearch := func(files []file) ([]FuncWithDistance, error) {
+		var refs usages
+		if *popularity {
+			refs = usages{}
+		}
+		opts := searchOptions{Normalize: *normalize, LooseWrappers: *looseWrappers, IgnoreCase: *ignoreCase, Typos: *typos, PreferExported: *preferExported, Usages: refs, Weights: w, Metric: m}
+		opts.NotArgs, opts.NotRets = parseExclusions(*notArg), parseExclusions(*notRet)
+
+		var gt *goTypes
+		if *match == "types" {
+			var err error
+			gt, err = loadGoTypes(roots)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// with -stream the functions of every file are searched as soon
+		// as it is parsed (without -tfidf and with the references so far)
+		var early *earlyResults
+		var each func([]Func) error
+		if *streamMode {
+			var err error
+			if early, err = newEarlyResults(os.Stderr, po); err != nil {
+				return nil, err
+			}
+			earlyOpts := opts
+			earlyOpts.Quiet = true
+			each = func(funcs []Func) error {
+				for _, q := range queries {
+					// the errors of the query are shown by the final search
+					fwd, err := search(funcs, q, *match, *exactArity, earlyOpts, gt)
+					if err != nil {
+						return nil
+					}
+					if len(queries) > 1 {
+						for i := range fwd {
+							fwd[i].Query = q
+						}
+					}
+					if err := early.add(fwd); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+		}
 
-	if match != nil {
-		inputs, outputs, err := getInputsAndOutput(uinput)
+		funcs, err := loadFuncs(files, refs, *quiet, *jobs, cache, each)
 		if err != nil {
-			log.Fatal(err)
+			return nil, err
+		}
+		if early != nil {
+			if err := early.close(); err != nil {
+				return nil, err
+			}
 		}
 
-		switch *match {
-		case "includes":
-			funcs = filterIncludes(funcs, inputs, outputs)
-		case "default":
-		// nothing here
-		default:
-			fmt.Printf("ERROR: Invalid match type '%s'\n", *match)
-			flag.Usage()
-			os.Exit(1)
+		if *embedURL != "" {
+			e := embedder{URL: *embedURL, Model: *embedModel, Key: os.Getenv("GLEE_EMBED_KEY")}
+			fwd, err := sortBySimilarity(funcs, queries, e, cache)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := printResults(fwd, po, os.Stdout); err != nil {
+				return nil, err
+			}
+			if *refineResults {
+				if err := refine(os.Stdin, fwd, searchOptions{Normalize: *normalize, IgnoreCase: *ignoreCase, LooseWrappers: *looseWrappers}, po); err != nil {
+					return nil, err
+				}
+			}
+			return fwd, nil
+		}
+
+		if refs != nil {
+			refs.removeDefinitions(funcs)
+		}
+
+		if *tfidf {
+			opts.TokenWeights = tokenWeights(funcs, opts)
+		}
+
+		// jsonl results are printed as soon as they are scored when all
+		// of them are shown in any order, the closest ones are only known
+		// once every function is scored
+		var stream func(FuncWithDistance) error
+		if po.Format == "jsonl" && !*refineResults && po.Limit < 0 && po.GroupBy == "" && !sortSet {
+			out, _ := getOutput(po, os.Stdout)
+			stream = streamResults(out, po)
+		}
+
+		results := [][]FuncWithDistance{}
+		for _, q := range queries {
+			if stream != nil {
+				opts.Stream = func(f FuncWithDistance) error {
+					if len(queries) > 1 {
+						f.Query = q
+					}
+					return stream(f)
+				}
+			}
+
+			fwd, err := search(funcs, q, *match, *exactArity, opts, gt)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, fwd)
+		}
+
+		fwd := mergeResults(queries, results)
+		if stream == nil {
+			if err := printResults(fwd, po, os.Stdout); err != nil {
+				return nil, err
+			}
+			if *refineResults {
+				if err := refine(os.Stdin, fwd, opts, po); err != nil {
+					return nil, err
+				}
+			}
 		}
+		return fwd, nil
 	}
 
-	fwd := sortByDistance(funcs, uinput)
+	fwd, err := runSearch(files)
+	if err != nil {
+		fatal(err)
+	}
+	if *watchMode {
+		err := watch(roots, dirs, func() error {
+			if stdoutIsTerminal() {
+				fmt.Print("\033[H\033[2J")
+			}
+			if *filesFrom == "" {
+				found, err := findAllFiles(roots, wo)
+				if err != nil {
+					return err
+				}
+				files = found
+			}
+			_, err := runSearch(files)
+			return err
+		})
+		if err != nil {
+			fatal(err)
+		}
+		return
+	}
+	if !hasResults(fwd, po) {
+		os.Exit(exitNoResults)
+	}
+}
 
-	for i, f := range fwd {
-		fmt.Println(f.Func)
+// queryArgs adds `--` before the first query starting with `->` in args
+// (eg: `glee '-> ( error )'`), so that it is not parsed as a flag of fs
+func queryArgs(fs *flag.FlagSet, args []string) []string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if strings.HasPrefix(arg, "->") {
+			return slices.Insert(slices.Clone(args), i, "--")
+		}
+		if arg == "-" || arg == "--" || !strings.HasPrefix(arg, "-") {
+			return args // the flags end there
+		}
+		name := strings.TrimLeft(arg, "-")
+		if strings.Contains(name, "=") {
+			continue
+		}
+		// skip the values of the flags, eg: `-e '-> ( error )'`
+		if f := fs.Lookup(name); f != nil {
+			if b, ok := f.Value.(interface{ IsBoolFlag() bool }); !ok || !b.IsBoolFlag() {
+				i++
+			}
+		}
+	}
+	return args
+}
+
+var matchModes = []string{"includes", "unordered", "superset", "types", "regex", "default"}
 
-		if i > 15 || f.Distance > 20 {
+// splitQueries splits the arguments into the queries and the
+// directories to search (the last arguments if path is true, that are
+// not signatures or, when the queries can be any text, that exist).
+// Queries can also have alternatives separated by `|`, eg:
+// `( str ) -> ( int ) | ( str ) -> ( float )`, unless split is false.
+func splitQueries(args []string, split, text, path bool) ([]string, []string) {
+	roots := []string{}
+	for path && len(args) > 1 {
+		last := args[len(args)-1]
+		if _, err := os.Stat(last); strings.Contains(last, "->") || (text && err != nil) {
 			break
 		}
+		roots = append([]string{last}, roots...)
+		args = args[:len(args)-1]
+	}
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+
+	queries := []string{}
+	for _, arg := range args {
+		// `|` is only a separator if every alternative is a whole
+		// query and not a union type like `-> ( string | number )`
+		alternatives := splitTopLevel(arg, "|")
+		for _, a := range alternatives {
+			if !split || !strings.Contains(a, "->") {
+				alternatives = []string{arg}
+				break
+			}
+		}
+
+		for _, a := range alternatives {
+			queries = append(queries, strings.TrimSpace(a))
+		}
+	}
+
+	return queries, roots
+}
+
+// search ranks funcs by their distance to a single query, using gt to
+// match with -match types
+func search(funcs []Func, query, match string, exactArity bool, opts searchOptions, gt *goTypes) ([]FuncWithDistance, error) {
+	logf := debugf
+	if opts.Quiet {
+		logf = func(verbosity, string, ...any) {}
+	}
+
+	name, uinput := splitNameQuery(query)
+	receiver, uinput := splitReceiverQuery(uinput)
+	if match != "regex" {
+		uinput = expandQueryAliases(uinput)
+
+		var notArgs, notRets []string
+		uinput, notArgs, notRets = splitExclusions(uinput)
+		opts.NotArgs = append(append([]string{}, opts.NotArgs...), notArgs...)
+		opts.NotRets = append(append([]string{}, opts.NotRets...), notRets...)
+	}
+	if err := checkExclusions(opts.NotArgs, opts.NotRets); err != nil {
+		return nil, err
+	}
+
+	// go types and regular expressions are matched as written
+	rawInput := uinput
+
+	opts.Name, opts.Receiver = name, receiver
+	if opts.Normalize {
+		uinput = normalizeType(uinput)
+	}
+	if opts.Receiver != "" {
+		funcs = filterMethods(funcs)
+	}
+
+	inputs, outputs, err := getInputsAndOutput(uinput)
+	if err != nil {
+		return nil, err
+	}
+	logf(1, "query %q: name %q, receiver %q, args %q, rets %q, excluding args %q and rets %q", query, name, receiver, inputs, outputs, opts.NotArgs, opts.NotRets)
+
+	switch match {
+	case "includes":
+		funcs = filterIncludes(funcs, inputs, outputs, opts)
+	case "unordered":
+		opts.Unordered = true
+	case "superset":
+		funcs = filterSuperset(funcs, inputs, outputs, opts)
+		opts.Superset = true
+	case "types":
+		inputs, outputs, _ := getInputsAndOutput(rawInput)
+		funcs = gt.filterTypes(funcs, inputs, outputs, opts)
+	case "regex":
+		inputs, outputs, err := getRegexInputsAndOutput(rawInput)
+		if err != nil {
+			return nil, err
+		}
+
+		funcs, err = filterRegex(funcs, inputs, outputs, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if exactArity {
+		funcs = filterArity(funcs, inputs, outputs)
+	}
+	funcs = filterExcluded(funcs, opts.NotArgs, opts.NotRets, opts)
+	logf(1, "query %q: ranking %d functions left after the filters", query, len(funcs))
+
+	start := time.Now()
+	fwd, err := sortByDistance(funcs, uinput, opts)
+	if err != nil {
+		return nil, err
+	}
+	logf(1, "query %q: ranked in %s", query, time.Since(start))
+	return fwd, nil
+}
+
+// mergeResults combines the results of several queries into one list,
+// keeping the closest match of every function along with the query it
+// matched
+func mergeResults(queries []string, results [][]FuncWithDistance) []FuncWithDistance {
+	if len(results) == 1 {
+		return results[0]
+	}
+
+	merged := []FuncWithDistance{}
+	for i, fwd := range results {
+		for _, f := range fwd {
+			f.Query = queries[i]
+			merged = append(merged, f)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool { return merged[i].Distance < merged[j].Distance })
+
+	seen := map[string]bool{}
+	unique := []FuncWithDistance{}
+	for _, f := range merged {
+		key := fmt.Sprintf("%s:%v", f.Func.Path, f.Func.Loc)
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, f)
+		}
 	}
+	return unique
+}
+
+// searchOptions configures how functions are matched against a query
+type searchOptions struct {
+	Normalize      bool     // normalize builtin types before matching
+	Unordered      bool     // ignore the order of arguments when ranking
+	Superset       bool     // rank by the number of extra arguments and results
+	LooseWrappers  bool     // ignore pointers, slices and varargs in types
+	IgnoreCase     bool     // match types ignoring case
+	Typos          int      // typos allowed in every name with -match includes
+	PreferExported bool     // rank exported functions higher at the same distance
+	NotArgs        []string // types the functions must not take
+	NotRets        []string // types the functions must not return
+	Usages         usages   // rank more used functions higher at the same distance, if set
+	Name           string   // name of the function to look for, if any
+	Receiver       string   // type the function has to be a method of, if any
+	Weights        weights
+	TokenWeights   map[string]float64           // weights of names in signatures, 1 if missing
+	Metric         metric                       // distance between signatures, levenshtein if nil
+	Stream         func(FuncWithDistance) error // called with every result as soon as it is scored, if set
+	Quiet          bool                         // do not log the query (eg: searching every file with -stream)
 }
 
-func filterIncludes(funcs []Func, inputs, outputs []string) []Func {
+// filterMethods keeps the functions that have a receiver
+func filterMethods(funcs []Func) []Func {
+	methods := []Func{}
+	for _, f := range funcs {
+		if f.Receiver != "" {
+			methods = append(methods, f)
+		}
+	}
+	return methods
+}
+
+func filterIncludes(funcs []Func, inputs, outputs []string, opts searchOptions) []Func {
 	filteredFuncs := []Func{}
+	labelled := labelledQuery.MatchString(strings.Join(inputs, ", "))
+	inputs, outputs = opts.prepareQuery(inputs), opts.prepareQuery(outputs)
 
 	for _, f := range funcs {
+		nf := opts.prepare(f, inputs, outputs, labelled)
+
 		// Early exit if we don't have enough values
-		if len(f.Args) < len(inputs) || len(f.Rets) < len(outputs) {
+		if len(nf.Args) < len(inputs) || len(nf.Rets) < len(outputs) {
 			continue
 		}
 
-		if !contains(f.Args, inputs) || !contains(f.Rets, outputs) {
+		// labelled arguments are also available so that queries can
+		// optionally match on labels
+		args := append(nf.LabelledArgs(), nf.Args...)
+		if !contains(args, inputs, opts.Typos) || !contains(nf.Rets, outputs, opts.Typos) {
 			continue
 		}
 
@@ -133,18 +700,143 @@ func filterIncludes(funcs []Func, inputs, outputs []string) []Func {
 	return filteredFuncs
 }
 
-func contains(items []string, tests []string) bool {
+// filterArity keeps the functions with as many arguments and results
+// as the query (nil inputs or outputs can be any number of them)
+func filterArity(funcs []Func, inputs, outputs []string) []Func {
+	filtered := []Func{}
+	for _, f := range funcs {
+		nf := f.expandVariadic(inputs)
+		if (inputs == nil || len(nf.Args) == len(nonEmpty(inputs))) && (outputs == nil || len(nf.Rets) == len(nonEmpty(outputs))) {
+			filtered = append(filtered, f)
+		}
+	}
+	return filtered
+}
+
+// filterSuperset keeps the functions that have all the inputs and
+// outputs in the query (in any order), along with any others. A
+// variadic parameter is not expanded here as it can take any number of
+// the inputs that are left.
+func filterSuperset(funcs []Func, inputs, outputs []string, opts searchOptions) []Func {
+	filtered := []Func{}
+	labelled := labelledQuery.MatchString(strings.Join(inputs, ", "))
+	inputs, outputs = opts.prepareQuery(inputs), opts.prepareQuery(outputs)
+
+	for _, f := range funcs {
+		nf := opts.prepareArgs(f.withParamNames(inputs, opts.IgnoreCase), inputs, outputs, labelled)
+
+		args := nf.Args
+		if labelled {
+			args = nf.LabelledArgs()
+		}
+		if hasAll(args, nonEmpty(inputs)) && hasAll(nf.Rets, nonEmpty(outputs)) {
+			filtered = append(filtered, f)
+		}
+	}
+
+	return filtered
+}
+
+// hasAll checks if every type in tests is a different one of items
+// (wildcards can be any of them). A variadic last item like `...T` can
+// be any number of the tests left that are T, or none of them.
+func hasAll(items []string, tests []string) bool {
+	elem, variadic := "", false
+	if len(items) > 0 {
+		elem, variadic = variadicElem(items[len(items)-1])
+	}
+	if len(items) < len(tests) && !variadic {
+		return false
+	}
+
+	used := make([]bool, len(items))
+	wildcards := 0
 	for _, test := range tests {
-		// escape [] , * and other special chars in input
-		for _, c := range []string{"[", "]", "*", ".", "{", "}", "(", ")"} {
-			test = strings.ReplaceAll(test, c, fmt.Sprintf("\\%s", c))
+		if isWildcard(test) {
+			wildcards++
+			continue
 		}
 
+		found := false
+		for i, item := range items {
+			if !used[i] && item == test {
+				used[i], found = true, true
+				break
+			}
+		}
+		if !found && !(variadic && (test == elem || isWildcard(elem))) {
+			return false
+		}
+	}
+	if variadic {
+		return true
+	}
+
+	unused := 0
+	for _, u := range used {
+		if !u {
+			unused++
+		}
+	}
+	return unused >= wildcards
+}
+
+// prepareQuery applies the options to the types in a query
+func (opts searchOptions) prepareQuery(types []string) []string {
+	if types == nil {
+		return nil
+	}
+	if opts.LooseWrappers {
+		types = stripWrappers(types)
+	}
+	if opts.IgnoreCase {
+		types = lower(types)
+	}
+	return types
+}
+
+// prepare applies the options to f before matching it against a query
+func (opts searchOptions) prepare(f Func, inputs, outputs []string, labelled bool) Func {
+	return opts.prepareArgs(f.withParamNames(inputs, opts.IgnoreCase).expandVariadic(inputs), inputs, outputs, labelled)
+}
+
+// prepareArgs is prepare for f with its parameter names and variadic
+// parameter already handled
+func (opts searchOptions) prepareArgs(f Func, inputs, outputs []string, labelled bool) Func {
+	nf := f
+	if opts.Normalize {
+		nf = nf.Normalized()
+	}
+	nf = nf.unify(inputs, outputs, labelled)
+	if opts.LooseWrappers {
+		nf.Args, nf.Rets = stripWrappers(nf.Args), stripWrappers(nf.Rets)
+	}
+	if opts.IgnoreCase {
+		nf.Args, nf.Labels, nf.Rets = lower(nf.Args), lower(nf.Labels), lower(nf.Rets)
+	}
+	return nf
+}
+
+func lower(items []string) []string {
+	lowered := []string{}
+	for _, item := range items {
+		lowered = append(lowered, strings.ToLower(item))
+	}
+	return lowered
+}
+
+// contains checks if every test is in one of the items, allowing typos
+// in the names of the types (see fuzzyContains)
+func contains(items []string, tests []string, typos int) bool {
+	for _, test := range tests {
+		if isWildcard(test) {
+			continue
+		}
+
+		// types are matched literally, use -match regex for patterns
 		available := false
 		for _, arg := range items {
-			// reg := regexp.MustCompile(fmt.Sprintf(`\b%s\b`, input))
-			reg := regexp.MustCompile(test)
-			if reg.MatchString(arg) {
+			if strings.Contains(arg, test) || (typos > 0 && fuzzyContains(arg, test, typos)) {
 				available = true
 				break
 			}
@@ -157,59 +849,152 @@ func contains(items []string, tests []string) bool {
 	return true
 }
 
+// getInputsAndOutput splits a query into its input and output types. A
+// partial query like `( io.Reader ) ->` or `-> ( error )` matches any
+// outputs or inputs and returns nil for them.
 func getInputsAndOutput(uinput string) ([]string, []string, error) {
-	inputs := []string{}
-	outputs := []string{}
-
 	uinput = strings.ReplaceAll(uinput, "(", " ")
 	uinput = strings.ReplaceAll(uinput, ")", " ")
 
-	splits := strings.Split(uinput, " -> ")
-	if len(splits) != 2 {
-		return nil, nil, fmt.Errorf("invalid input")
-	}
-
-	sps := strings.Split(splits[0], ",")
-	for _, sp := range sps {
-		inputs = append(inputs, strings.TrimSpace(sp))
+	types := func(s string) []string {
+		res := []string{}
+		for _, sp := range strings.Split(s, ",") {
+			res = append(res, strings.TrimSpace(sp))
+		}
+		return res
 	}
 
-	sps = strings.Split(splits[1], ",")
-	for _, sp := range sps {
-		outputs = append(outputs, strings.TrimSpace(sp))
+	trimmed := strings.TrimSpace(uinput)
+	splits := strings.Split(uinput, " -> ")
+	switch {
+	case len(splits) == 2:
+		return types(splits[0]), types(splits[1]), nil
+	case strings.HasSuffix(trimmed, "->") && !strings.Contains(strings.TrimSuffix(trimmed, "->"), " -> "):
+		return types(strings.TrimSuffix(trimmed, "->")), nil, nil
+	case strings.HasPrefix(trimmed, "->") && !strings.Contains(strings.TrimPrefix(trimmed, "->"), " -> "):
+		return nil, types(strings.TrimPrefix(trimmed, "->")), nil
 	}
 
-	return inputs, outputs, nil
+	return nil, nil, fmt.Errorf("invalid input")
 }
 
-func getLanguage(filename string) string {
-	lang := ""
-	switch filepath.Ext(filename) {
-	case ".go":
-		lang = "golang"
-	}
-	return lang
-}
+// labelledQuery checks if a query contains argument labels (eg: `from: String`)
+var labelledQuery = regexp.MustCompile(`(^|[(,])\s*\w+:\s`)
 
-// sortByDistance sorts the items by levenshtein distance
-// TODO(meain): make it so that ordering of args do not affect lev distance
-func sortByDistance(funcs []Func, uinput string) []FuncWithDistance {
+// sortByDistance sorts the items by the (token) edit distance of their
+// name, arguments and results, weighted by opts.Weights. It fails if
+// opts.Stream does. The order of the arguments counts on purpose,
+// `-match unordered` is there to ignore it.
+func sortByDistance(funcs []Func, uinput string, opts searchOptions) ([]FuncWithDistance, error) {
 	distanceMap := []struct {
-		Func     Func
-		Distance int
+		Func      Func
+		Distance  float64
+		Score     score
+		Reordered bool
 	}{}
 
+	inputs, outputs, err := getInputsAndOutput(uinput)
+	wildcards := err == nil && (strings.Contains(uinput, "_") || strings.Contains(uinput, "?"))
+
+	labelled := labelledQuery.MatchString(uinput)
 	for _, f := range funcs {
-		distance := levenshtein.ComputeDistance(uinput, f.Signature())
+		nf := f.withParamNames(inputs, opts.IgnoreCase)
+		if opts.Normalize {
+			nf = nf.Normalized()
+		}
+		nf = nf.expandVariadic(inputs)
+		argsCost, retsCost := nf.unifyCost(inputs, outputs, labelled)
+		nf = nf.unify(inputs, outputs, labelled)
+
+		reordered := false
+		if opts.Unordered {
+			nf, reordered = nf.reorderArgs(inputs, labelled)
+		}
+
+		signature := nf.Signature()
+		if labelled {
+			signature = nf.LabelledSignature()
+		}
+
+		query := uinput
+		if wildcards {
+			query = fillWildcards(inputs, outputs, nf, labelled)
+		}
+
+		// rank functions with the same wrappers as the query higher
+		part := func(q, s string) float64 {
+			d := tokenDistance(q, s, opts)
+			if opts.LooseWrappers {
+				if loose := tokenDistance(wrapper.ReplaceAllString(q, ""), wrapper.ReplaceAllString(s, ""), opts); loose < d {
+					d = loose + 1
+				}
+			}
+			return d
+		}
+
+		queryArgs, queryRets := splitSignature(query)
+		args, rets := splitSignature(signature)
+
+		// partial queries do not have inputs or outputs
+		w := opts.Weights
+		var sc score
+		switch {
+		case opts.Superset:
+			sc.Args = w.Args * float64(len(nf.Args)-len(nonEmpty(inputs)))
+			sc.Rets = w.Rets * float64(len(nf.Rets)-len(nonEmpty(outputs)))
+		default:
+			if inputs != nil {
+				sc.Args = w.Args * (part(queryArgs, args) + argsCost)
+				sc.Arity += w.Arity * float64(abs(len(nf.Args)-len(nonEmpty(inputs))))
+			}
+			if outputs != nil {
+				sc.Rets = w.Rets * (part(queryRets, rets) + retsCost)
+				sc.Arity += w.Arity * float64(abs(len(nf.Rets)-len(nonEmpty(outputs))))
+			}
+		}
+		if opts.Name != "" {
+			sc.Name += w.Name * float64(nameDistance(opts.Name, f))
+		}
+		if opts.Receiver != "" {
+			sc.Name += w.Name * float64(receiverDistance(opts.Receiver, f))
+		}
+		distance := sc.Args + sc.Rets + sc.Arity + sc.Name
 		distanceMap = append(distanceMap, struct {
-			Func     Func
-			Distance int
-		}{Func: f, Distance: distance})
+			Func      Func
+			Distance  float64
+			Score     score
+			Reordered bool
+		}{Func: f, Distance: distance, Score: sc, Reordered: reordered})
+
+		if opts.Stream != nil {
+			if err := opts.Stream(FuncWithDistance{Func: f, Distance: distance, Score: &sc}); err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	// sort by distance
+	// sort by distance, preferring functions with the arguments in
+	// the same order as the query, outside of tests and (optionally)
+	// more used and exported, and then by location and name so that
+	// the order does not change between runs
 	sort.Slice(distanceMap, func(i, j int) bool {
-		return distanceMap[i].Distance < distanceMap[j].Distance
+		a, b := distanceMap[i], distanceMap[j]
+		if a.Distance != b.Distance {
+			return a.Distance < b.Distance
+		}
+		if a.Reordered != b.Reordered {
+			return !a.Reordered
+		}
+		if a.Func.Test != b.Func.Test {
+			return !a.Func.Test
+		}
+		if ua, ub := opts.Usages[a.Func.Name], opts.Usages[b.Func.Name]; ua != ub {
+			return ua > ub
+		}
+		if ea, eb := a.Func.Exported(), b.Func.Exported(); opts.PreferExported && ea != eb {
+			return ea
+		}
+		return byLocation(a.Func, b.Func)
 	})
 
 	fwd := []FuncWithDistance{}
@@ -217,71 +1002,534 @@ func sortByDistance(funcs []Func, uinput string) []FuncWithDistance {
 		fwd = append(fwd, FuncWithDistance{
 			Func:     d.Func,
 			Distance: d.Distance,
+			Score:    &d.Score,
 		})
 	}
 
-	return fwd
+	return fwd, nil
+}
+
+// byLocation orders functions by path, line and then name
+func byLocation(a, b Func) bool {
+	if a.Path != b.Path {
+		return a.Path < b.Path
+	}
+	if a.Loc[0] != b.Loc[0] {
+		return a.Loc[0] < b.Loc[0]
+	}
+	return a.FullName() < b.FullName()
 }
 
 type Func struct {
-	Path string
-	Loc  []int
-	Name string
-	Args []string
-	Rets []string
+	Path     string
+	Loc      []int // row, column, byte offset and (if known) end row and column, 0 based
+	Name     string
+	Receiver string // type the function is defined on, if any
+	Args     []string
+	Labels   []string // argument labels (if the language has them) for Args
+	Params   []string // parameter names for Args, if known
+	Rets     []string
+
+	TypeParams []string // names of the type parameters of a generic function
+	Language   string   // language of the file it is defined in
+	Test       bool     // defined in a test file
+	Doc        string   // comment or docstring documenting the function
+	BodyLoc    []int    // first and last row of the body, 0 based, if it has one
 }
 
 type FuncWithDistance struct {
 	Func     Func
-	Distance int
+	Distance float64
+	Score    *score // parts of Distance, if it is an edit distance
+	Query    string // query that matched, if there were several
+}
+
+// score is how much the name (and receiver), arguments, results and
+// arity differences add to the distance of a function
+type score struct {
+	Name, Args, Rets, Arity float64
 }
 
 func (f Func) String() string {
-	return fmt.Sprintf(
-		"%s:%s:%s:%s (%s) -> (%s)",
-		f.Path,
-		strconv.Itoa(f.Loc[0]),
-		strconv.Itoa(f.Loc[1]),
-		f.Name,
-		strings.Join(f.Args, ", "),
-		strings.Join(f.Rets, ", "),
-	)
+	return fmt.Sprintf("%s:%d:%d:%s", f.Path, f.Loc[0], f.Loc[1], describe(f))
+}
+
+// LabelledArgs returns the arguments prefixed with their labels (eg:
+// `from: String`), if there are any.
+func (f Func) LabelledArgs() []string {
+	args := []string{}
+	for i, a := range f.Args {
+		if i < len(f.Labels) && f.Labels[i] != "" {
+			a = f.Labels[i] + ": " + a
+		}
+		args = append(args, a)
+	}
+	return args
+}
+
+// Exported checks if f is part of the public API of a Go package,
+// which is the case if its name (and receiver) start with an upper case
+// letter. Functions in other languages are always exported.
+func (f Func) Exported() bool {
+	if filepath.Ext(f.Path) != ".go" {
+		return true
+	}
+
+	upper := func(name string) bool {
+		r, _ := utf8.DecodeRuneInString(name)
+		return unicode.IsUpper(r)
+	}
+	return upper(f.Name) && (f.Receiver == "" || upper(f.Receiver))
+}
+
+// FullName is the name of the function qualified with its receiver
+func (f Func) FullName() string {
+	if f.Receiver == "" {
+		return f.Name
+	}
+	return f.Receiver + "." + f.Name
 }
 
 func (f Func) Signature() string {
 	return fmt.Sprintf("( %s ) -> ( %s )", strings.Join(f.Args, ", "), strings.Join(f.Rets, ", "))
 }
 
+// LabelledSignature is Signature, but with argument labels
+func (f Func) LabelledSignature() string {
+	return fmt.Sprintf("( %s ) -> ( %s )", strings.Join(f.LabelledArgs(), ", "), strings.Join(f.Rets, ", "))
+}
+
+// walkOptions configures which files are searched and how their paths
+// are shown
+type walkOptions struct {
+	NoTests  bool     // skip test files
+	AbsPaths bool     // absolute paths instead of ones relative to the working directory
+	NoIgnore bool     // do not skip the paths in .gitignore and .ignore files
+	Hidden   bool     // do not skip hidden files and directories (eg: .cache)
+	Follow   bool     // walk the directories symlinks point to
+	MaxSize  fileSize // skip the files larger than this many bytes (if not 0)
+	Exclude  []string // globs of the paths to skip, like the patterns of .gitignore files
+	Include  []string // globs of the files to search (all if empty), like Exclude
+
+	Dirs func(path string) // called with every directory that is walked, if set
+}
+
+// walkFlags defines the flags of wo in fs
+func walkFlags(fs *flag.FlagSet, wo *walkOptions) {
+	fs.Var((*stringList)(&wo.Exclude), "exclude", "skip the files and directories matching this glob, can be repeated (eg: 'vendor/**' or '*.pb.go')")
+	fs.Var((*stringList)(&wo.Include), "include", "only search the files matching this glob, can be repeated (eg: 'pkg/**/*.go')")
+	fs.BoolVar(&wo.NoIgnore, "no-ignore", false, "also search the files ignored by .gitignore and .ignore files")
+	fs.BoolVar(&wo.Hidden, "hidden", false, "also search hidden files and directories (eg: .config)")
+	fs.BoolVar(&wo.Follow, "follow", false, "follow symlinks to directories")
+	wo.MaxSize = defaultMaxFileSize
+	fs.Var(&wo.MaxSize, "max-filesize", "skip the files larger than this (eg: 512K or 2M), 0 to search all of them")
+	fs.BoolVar(&wo.NoTests, "no-tests", false, "skip test files (eg: *_test.go)")
+}
+
+// findFiles returns the files in root that are in one of the languages.
+// The paths are relative to the working directory (unless they are
+// outside of it and root is absolute) without wo.AbsPaths.
+func findFiles(root string, wo walkOptions) ([]file, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	var ig *ignorer
+	if !wo.NoIgnore {
+		if ig, err = newIgnorer(root); err != nil {
+			return nil, err
+		}
+	}
+
+	excluded, included := &ignorer{}, &ignorer{}
+	if err := excluded.add(root, wo.Exclude); err != nil {
+		return nil, err
+	}
+	if err := included.add(root, wo.Include); err != nil {
+		return nil, err
+	}
+
+	files := []file{}
+	visited := map[string]bool{} // the real paths of the directories, with wo.Follow
+
+	var walk filepath.WalkFunc
+	walk = func(path string, info os.FileInfo, err error) error {
+		if err == nil {
+			// symlinks to directories are walked like the ones they
+			// point to (always for root), the others are searched
+			// like files
+			link := false
+			if info.Mode()&os.ModeSymlink != 0 && (wo.Follow || path == root) {
+				if target, err := os.Stat(path); err == nil && target.IsDir() {
+					info, link = target, true
+				}
+			}
+
+			// paths passed explicitly are never ignored
+			skipped := ""
+			switch {
+			case path == root:
+			case !wo.Hidden && strings.HasPrefix(info.Name(), "."):
+				skipped = "hidden"
+			case ig != nil && ig.matches(path, info.IsDir()):
+				skipped = "ignored"
+			case len(excluded.rules) > 0 && excluded.matches(path, info.IsDir()):
+				skipped = "excluded"
+			}
+			if skipped != "" {
+				debugf(1, "skipping %s (%s)", path, skipped)
+				if info.IsDir() && !link {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if link {
+				// walking it with a trailing separator follows it
+				return filepath.Walk(path+string(filepath.Separator), walk)
+			}
+
+			if info.IsDir() {
+				if wo.Follow {
+					// skip the directories seen through another link
+					// (or their own, in a cycle)
+					real, err := filepath.EvalSymlinks(path)
+					if err != nil || visited[real] {
+						return filepath.SkipDir
+					}
+					visited[real] = true
+				}
+				if wo.Dirs != nil {
+					wo.Dirs(path)
+				}
+				if ig != nil {
+					return ig.load(path)
+				}
+			} else if path != root && len(included.rules) > 0 && !included.matches(path, false) {
+				debugf(2, "skipping %s (not included)", path)
+			} else if lang := getLanguage(path); lang != "" {
+				test := isTestFile(lang, path)
+				switch {
+				case test && wo.NoTests:
+					debugf(2, "skipping %s (test file)", path)
+				case path != root && tooLarge(path, info, int64(wo.MaxSize)):
+					debugf(1, "skipping %s (larger than -max-filesize %s)", path, &wo.MaxSize)
+				default:
+					files = append(files, file{Language: lang, Path: displayPath(path, cwd, wo.AbsPaths), Test: test})
+				}
+			}
+		} else {
+			debugf(1, "skipping %s: %s", path, err)
+		}
+		return nil
+	}
+	err = filepath.Walk(root, walk)
+	return files, err
+}
+
+// findAllFiles returns the files in all the roots, once even if the
+// roots overlap
+func findAllFiles(roots []string, wo walkOptions) ([]file, error) {
+	start := time.Now()
+	files := []file{}
+	seen := map[string]bool{}
+	for _, root := range roots {
+		found, err := findFiles(root, wo)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range found {
+			if !seen[f.Path] {
+				seen[f.Path] = true
+				files = append(files, f)
+			}
+		}
+	}
+	debugf(1, "found %d files in %s", len(files), time.Since(start))
+	return files, nil
+}
+
+// printFiles prints the files that would be searched in roots (or the
+// ones listed in filesFrom if it is set), ending with a NUL with print0,
+// and checks if there are any
+func printFiles(roots []string, filesFrom string, wo walkOptions, print0 bool, w io.Writer) (bool, error) {
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+
+	var files []file
+	var err error
+	if filesFrom != "" {
+		files, err = readFileList(filesFrom, wo)
+	} else {
+		files, err = findAllFiles(roots, wo)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	end := "\n"
+	if print0 {
+		end = "\x00"
+	}
+	bw := bufio.NewWriter(w)
+	for _, f := range files {
+		fmt.Fprint(bw, f.Path, end)
+	}
+	return len(files) > 0, bw.Flush()
+}
+
+// readQueries reads the queries from r, one per line, so that they do
+// not have to be quoted for the shell (eg: by editor plugins)
+func readQueries(r io.Reader) ([]string, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			queries = append(queries, line)
+		}
+	}
+	return queries, nil
+}
+
+// readFileList reads the files to search from a list of paths (one per
+// line, or separated by NULs like the output of `git ls-files -z`) in
+// name, or stdin if it is "-". Files in other languages are skipped.
+func readFileList(name string, wo walkOptions) ([]file, error) {
+	var content []byte
+	var err error
+	if name == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	sep := "\n"
+	if strings.Contains(string(content), "\x00") {
+		sep = "\x00"
+	}
+
+	files := []file{}
+	for _, path := range strings.Split(string(content), sep) {
+		if path = strings.TrimRight(path, "\r"); path == "" {
+			continue
+		}
+		if info, err := os.Lstat(path); err == nil && tooLarge(path, info, int64(wo.MaxSize)) {
+			debugf(1, "skipping %s (larger than -max-filesize %s)", path, &wo.MaxSize)
+			continue
+		}
+		lang := getLanguage(path)
+		test := isTestFile(lang, path)
+		if lang != "" && !(test && wo.NoTests) {
+			files = append(files, file{Language: lang, Path: displayPath(path, cwd, wo.AbsPaths), Test: test})
+		}
+	}
+	return files, nil
+}
+
+// displayPath is path made absolute (with abs) or relative to cwd
+func displayPath(path, cwd string, abs bool) string {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	if abs {
+		return absPath
+	}
+
+	rel, err := filepath.Rel(cwd, absPath)
+	if err != nil || (filepath.IsAbs(path) && (rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)))) {
+		return path
+	}
+	return rel
+}
+
+// parsedFile is what a worker of loadFuncs found in a file
+type parsedFile struct {
+	funcs    []Func
+	source   []byte // only kept to count the references
+	cached   bool
+	entry    indexEntry // of the file in the index of cache
+	duration time.Duration
+	err      error
+}
+
+// loadFuncs parses the functions in files, jobs of them at once (one
+// per CPU if it is 0), counting the references to them in refs if it is
+// not nil. The functions are in the order of files however long each
+// one takes. The progress is shown on stderr unless quiet is set, and
+// the functions of the files that did not change are read from cache if
+// it is not nil. If each is not nil, it is called with the functions of
+// every file as soon as it is parsed (and the progress is not shown).
+func loadFuncs(files []file, refs usages, quiet bool, jobs int, cache *funcCache, each func([]Func) error) ([]Func, error) {
+	p := newProgress(len(files), quiet || verbose > 0 || each != nil)
+	defer p.done()
+
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+
+	results := make([]chan parsedFile, len(files))
+	for i := range results {
+		results[i] = make(chan parsedFile, 1)
+	}
+	next := make(chan int)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		defer close(next)
+		for i := range files {
+			select {
+			case next <- i:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	for range jobs {
+		go func() {
+			for i := range next {
+				results[i] <- parseFile(files[i], refs != nil, cache)
+			}
+		}()
+	}
+
+	start := time.Now()
+	funcs := []Func{}
+	cached := 0
+	parsed := map[string]indexEntry{}
+	for i, f := range files {
+		r := <-results[i]
+		if r.err != nil {
+			return nil, r.err
+		}
+		if refs != nil {
+			refs.addReferences(r.source)
+		}
+
+		if r.entry.Key != "" {
+			parsed[f.Path] = r.entry
+		}
+		if each != nil {
+			if err := each(r.funcs); err != nil {
+				return nil, err
+			}
+		}
+
+		funcs = append(funcs, r.funcs...)
+		p.add(len(r.funcs))
+		if r.cached {
+			cached++
+			debugf(2, "loaded %s from the cache: %d functions in %s", f.Path, len(r.funcs), r.duration)
+		} else {
+			debugf(2, "parsed %s: %d functions in %s", f.Path, len(r.funcs), r.duration)
+		}
+	}
+	debugf(1, "parsed %d functions in %d files (%d from the cache) in %s with %d jobs", len(funcs), len(files), cached, time.Since(start), jobs)
+	if cache != nil {
+		if err := cache.saveIndex(parsed); err != nil {
+			debugf(1, "not saving the cache index: %s", err)
+		}
+	}
+	return funcs, nil
+}
+
+// parseFile reads and parses the functions in f, keeping the source
+// with keepSource. If cache is not nil, the functions are taken from it
+// when the file did not change (without reading it unless the source is
+// kept) or has the same content as a file already parsed.
+func parseFile(f file, keepSource bool, cache *funcCache) parsedFile {
+	start := time.Now()
+	r := parsedFile{}
+	if cache != nil {
+		// before reading, so that a change while reading is parsed again
+		info, err := os.Stat(f.Path)
+		if err != nil {
+			return parsedFile{err: err}
+		}
+		r.entry = indexEntry{Language: f.Language, Version: languages[f.Language].Version, ModTime: info.ModTime().UnixNano(), Size: info.Size()}
+		if key, ok := cache.unchanged(f, info); ok && !keepSource {
+			r.entry.Key = key
+			r.funcs, r.cached = cache.get(key)
+		}
+	}
+
+	if !r.cached {
+		sourceCode, err := os.ReadFile(f.Path)
+		if err != nil {
+			return parsedFile{err: err}
+		}
+		if keepSource {
+			r.source = sourceCode
+		}
+		if cache != nil {
+			r.entry.Key = cache.key(f, sourceCode)
+			r.funcs, r.cached = cache.get(r.entry.Key)
+		}
+		if !r.cached {
+			if r.funcs, err = getFuncs(sourceCode, f); err != nil {
+				return parsedFile{err: err}
+			}
+			if cache != nil {
+				if err := cache.put(r.entry.Key, r.funcs); err != nil {
+					debugf(1, "not caching %s: %s", f.Path, err)
+				}
+			}
+		}
+	}
+	for i := range r.funcs {
+		r.funcs[i].Language, r.funcs[i].Test = f.Language, f.Test
+	}
+
+	r.duration = time.Since(start)
+	return r
+}
+
 func getFuncs(sourceCode []byte, f file) ([]Func, error) {
-	var (
-		lang         *sitter.Language
-		queryPattern map[string]string
-	)
-
-	// TODO(meain): Unify this and getLanguage conditional into a global dict
-	switch f.Language {
-	case "golang":
-		lang = golang.GetLanguage()
-		queryPattern = map[string]string{
-			"function": "(function_declaration name: (identifier) @name) @func",
-			"input":    "(function_declaration parameters: (parameter_list (parameter_declaration type: (_) @type)))",
-			"output": `(function_declaration result: (parameter_list (parameter_declaration type: (_) @type)))
-                       (function_declaration result: [(type_identifier) (pointer_type) (slice_type)] @type)`,
-		}
-	default:
+	l, ok := languages[f.Language]
+	if !ok {
 		return nil, fmt.Errorf("language %s not supported", f.Language)
 	}
 
+	if l.Parse != nil {
+		return l.Parse(sourceCode, f.Path), nil
+	}
+
+	return parseFuncs(sourceCode, f.Path, l), nil
+}
+
+// parseFuncs uses the tree-sitter grammar and queries of l to find the
+// functions in sourceCode
+func parseFuncs(sourceCode []byte, path string, l language) []Func {
+	lang := l.Grammar()
+
 	node, err := sitter.ParseCtx(context.Background(), sourceCode, lang)
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
 
 	query := map[string]*sitter.Query{}
-	for k, v := range queryPattern {
+	for k, v := range l.Queries {
+		if strings.TrimSpace(v) == "" {
+			continue
+		}
+
 		q, err := sitter.NewQuery([]byte(v), lang)
 		if err != nil {
-			log.Fatal(err)
+			fatal(err)
 		}
 		query[k] = q
 	}
@@ -290,6 +1538,7 @@ func getFuncs(sourceCode []byte, f file) ([]Func, error) {
 	cursor.Exec(query["function"], node)
 
 	funcs := []Func{}
+	seen := map[uint32]bool{}
 
 	for {
 		m, ok := cursor.NextMatch()
@@ -298,26 +1547,90 @@ func getFuncs(sourceCode []byte, f file) ([]Func, error) {
 		}
 
 		m = cursor.FilterPredicates(m, sourceCode)
-		point := m.Captures[0].Node.StartPoint()
+
+		var fn, name, body *sitter.Node
+		for _, c := range m.Captures {
+			switch query["function"].CaptureNameForId(c.Index) {
+			case "func":
+				fn = c.Node
+			case "name":
+				name = c.Node
+			case "body":
+				body = c.Node
+			}
+		}
+		// a function could be matched by multiple patterns
+		if fn == nil || name == nil || seen[fn.StartByte()] {
+			continue
+		}
+		seen[fn.StartByte()] = true
+
+		if body == nil {
+			body = fn.ChildByFieldName("body")
+		}
+
+		// only look for types in the function header
+		end := fn.EndByte()
+		if body != nil {
+			end = body.StartByte()
+		}
+
+		point, endPoint := fn.StartPoint(), fn.EndPoint()
 
 		f := Func{
-			Path: f.Path,
-			Loc:  []int{int(point.Row), int(point.Column)},
-			Name: m.Captures[1].Node.Content(sourceCode),
+			Path: path,
+			Loc:  []int{int(point.Row), int(point.Column), int(fn.StartByte()), int(endPoint.Row), int(endPoint.Column)},
+			Name: name.Content(sourceCode),
 		}
 
-		f.Args = getTypes(m.Captures[0].Node, sourceCode, query["input"])
-		f.Rets = getTypes(m.Captures[0].Node, sourceCode, query["output"])
+		f.Args, f.Params = getParams(fn, end, sourceCode, query["input"], l.Type)
+		f.Rets = getTypes(fn, end, sourceCode, query["output"], l.Type)
+		f.TypeParams = getTypes(fn, end, sourceCode, query["generics"], nil)
+		f.Doc = docComment(fn, body, sourceCode)
+		if body != nil {
+			f.BodyLoc = bodyNodeRows(body)
+		}
+
+		if l.Signature != nil {
+			l.Signature(fn, sourceCode, &f)
+		}
 
 		funcs = append(funcs, f)
 	}
 
-	return funcs, nil
+	return funcs
 }
 
-func getTypes(node *sitter.Node, sourceCode []byte, query *sitter.Query) []string {
-	types := []string{}
+// getTypes returns the content of the first capture for every match of
+// query within node that starts before end. Captures nested inside an
+// already captured type (eg: params of a function type) are skipped.
+// If format is not nil, it is used to render the captured nodes.
+func getTypes(
+	node *sitter.Node,
+	end uint32,
+	sourceCode []byte,
+	query *sitter.Query,
+	format func(*sitter.Node, []byte) string,
+) []string {
+	types, _ := getParams(node, end, sourceCode, query, format)
+	return types
+}
+
+// getParams is getTypes, but also returns the name of the parameter
+// every type belongs to (empty if it is not known)
+func getParams(
+	node *sitter.Node,
+	end uint32,
+	sourceCode []byte,
+	query *sitter.Query,
+	format func(*sitter.Node, []byte) string,
+) ([]string, []string) {
+	types, names := []string{}, []string{}
+	if query == nil {
+		return types, names
+	}
 
+	var last uint32
 	cursor := sitter.NewQueryCursor()
 	cursor.Exec(query, node)
 	for {
@@ -327,9 +1640,85 @@ func getTypes(node *sitter.Node, sourceCode []byte, query *sitter.Query) []strin
 		}
 
 		m = cursor.FilterPredicates(m, sourceCode)
+		if len(m.Captures) == 0 {
+			continue
+		}
 
-		types = append(types, m.Captures[0].Node.Content(sourceCode))
+		n := m.Captures[0].Node
+		if n.StartByte() >= end || n.StartByte() < last {
+			continue
+		}
+		last = n.EndByte()
+
+		t := n.Content(sourceCode)
+		if format != nil {
+			t = format(n, sourceCode)
+		}
+		if t == "" {
+			continue
+		}
+
+		types = append(types, t)
+		names = append(names, paramName(n, sourceCode))
 	}
 
-	return types
+	return types, names
+}
+
+// paramName finds the name of the parameter with the type n. That is
+// the name (or pattern/declarator) field or the identifier next to n in
+// the first enclosing node that looks like a parameter in most grammars.
+func paramName(n *sitter.Node, sourceCode []byte) string {
+	isName := func(c *sitter.Node) bool {
+		t := c.Type()
+		return (strings.HasSuffix(t, "identifier") && !strings.Contains(t, "type")) || t == "variable_name"
+	}
+	name := func(c *sitter.Node) string {
+		// C declarators can be nested, eg: `char *name[]`
+		for d := c.ChildByFieldName("declarator"); d != nil; d = d.ChildByFieldName("declarator") {
+			c = d
+		}
+		// and so can patterns, eg: `...rest`
+		if !isName(c) && c.NamedChildCount() == 1 {
+			c = c.NamedChild(0)
+		}
+		if isName(c) {
+			return strings.TrimPrefix(c.Content(sourceCode), "$")
+		}
+		return ""
+	}
+
+	same := func(c *sitter.Node) bool {
+		return c.StartByte() == n.StartByte() && c.EndByte() == n.EndByte()
+	}
+
+	p := n
+	if !strings.Contains(n.Type(), "parameter") {
+		p = n.Parent()
+	}
+	for depth := 0; depth < 2 && p != nil; depth, p = depth+1, p.Parent() {
+		t := p.Type()
+		if strings.Contains(t, "parameters") || strings.Contains(t, "list") || strings.Contains(t, "function") || strings.Contains(t, "method") {
+			break
+		}
+
+		for _, field := range []string{"name", "pattern", "declarator"} {
+			if c := p.ChildByFieldName(field); c != nil && !same(c) {
+				if nm := name(c); nm != "" {
+					return nm
+				}
+			}
+		}
+		for i := 0; i < int(p.NamedChildCount()); i++ {
+			if c := p.NamedChild(i); !same(c) && (isName(c) || c.Type() == "variable_declarator") {
+				if nm := name(c); nm != "" {
+					return nm
+				}
+				if c.ChildByFieldName("name") != nil {
+					return c.ChildByFieldName("name").Content(sourceCode)
+				}
+			}
+		}
+	}
+	return ""
 }
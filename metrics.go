@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// metric is the distance between two lists of atoms (tokens of a
+// signature) where cost is how much adding or removing an atom costs
+type metric func(ta, tb []string, cost func(string) float64) float64
+
+var metrics = map[string]metric{
+	"levenshtein":  editDistance,
+	"damerau":      damerauDistance,
+	"jaro-winkler": jaroWinklerDistance,
+	"lcs":          lcsDistance,
+}
+
+// getMetric returns the metric called name
+func getMetric(name string) (metric, error) {
+	m, ok := metrics[name]
+	if !ok {
+		names := []string{}
+		for n := range metrics {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("invalid metric '%s' (options: %s)", name, strings.Join(names, ", "))
+	}
+	return m, nil
+}
+
+// damerauDistance is editDistance, but swapping two adjacent atoms
+// (eg: `( int, string )` and `( string, int )` have the types swapped
+// around a `,`) costs as much as replacing one of them
+func damerauDistance(ta, tb []string, cost func(string) float64) float64 {
+	d := make([][]float64, len(ta)+1)
+	for i := range d {
+		d[i] = make([]float64, len(tb)+1)
+		if i > 0 {
+			d[i][0] = d[i-1][0] + cost(ta[i-1])
+		}
+	}
+	for j := 1; j <= len(tb); j++ {
+		d[0][j] = d[0][j-1] + cost(tb[j-1])
+	}
+
+	for i := 1; i <= len(ta); i++ {
+		for j := 1; j <= len(tb); j++ {
+			replace := 0.0
+			if ta[i-1] != tb[j-1] {
+				replace = (cost(ta[i-1]) + cost(tb[j-1])) / 2
+			}
+			d[i][j] = min(d[i-1][j]+cost(ta[i-1]), d[i][j-1]+cost(tb[j-1]), d[i-1][j-1]+replace)
+
+			// transposition of the atoms around a shared one, so
+			// that swapping arguments is a single edit
+			if i > 2 && j > 2 && ta[i-1] == tb[j-3] && ta[i-3] == tb[j-1] && ta[i-2] == tb[j-2] && ta[i-1] != ta[i-3] {
+				d[i][j] = min(d[i][j], d[i-3][j-3]+(cost(ta[i-1])+cost(ta[i-3]))/2)
+			}
+			if i > 1 && j > 1 && ta[i-1] == tb[j-2] && ta[i-2] == tb[j-1] && ta[i-1] != ta[i-2] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+(cost(ta[i-1])+cost(ta[i-2]))/2)
+			}
+		}
+	}
+
+	return d[len(ta)][len(tb)]
+}
+
+// lcsDistance is the cost of the atoms that are not part of the
+// longest common subsequence of ta and tb, ie: the edit distance when
+// only insertions and deletions are allowed
+func lcsDistance(ta, tb []string, cost func(string) float64) float64 {
+	total := 0.0
+	for _, t := range ta {
+		total += cost(t)
+	}
+	for _, t := range tb {
+		total += cost(t)
+	}
+
+	// the largest total cost of a common subsequence
+	prev := make([]float64, len(tb)+1)
+	curr := make([]float64, len(tb)+1)
+	for i := 1; i <= len(ta); i++ {
+		for j := 1; j <= len(tb); j++ {
+			if ta[i-1] == tb[j-1] {
+				curr[j] = prev[j-1] + cost(ta[i-1])
+			} else {
+				curr[j] = max(prev[j], curr[j-1])
+			}
+		}
+		prev, curr = curr, prev
+	}
+
+	return total - 2*prev[len(tb)]
+}
+
+// jaroWinklerDistance is 1 - the jaro-winkler similarity of ta and tb,
+// scaled by the length of the longer one to be comparable to the other
+// metrics. It favours signatures with the same start and does not use
+// cost.
+func jaroWinklerDistance(ta, tb []string, cost func(string) float64) float64 {
+	longest := max(len(ta), len(tb))
+	if longest == 0 {
+		return 0
+	}
+	return (1 - jaroWinkler(ta, tb)) * float64(longest)
+}
+
+func jaroWinkler(ta, tb []string) float64 {
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+
+	window := max(len(ta), len(tb))/2 - 1
+	window = max(window, 0)
+
+	matchedA := make([]bool, len(ta))
+	matchedB := make([]bool, len(tb))
+	matches := 0
+	for i := range ta {
+		for j := max(0, i-window); j < min(len(tb), i+window+1); j++ {
+			if !matchedB[j] && ta[i] == tb[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0
+	}
+
+	transpositions := 0
+	j := 0
+	for i := range ta {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ta[i] != tb[j] {
+			transpositions++
+		}
+		j++
+	}
+
+	m := float64(matches)
+	jaro := (m/float64(len(ta)) + m/float64(len(tb)) + (m-float64(transpositions)/2)/m) / 3
+
+	prefix := 0
+	for prefix < min(4, len(ta), len(tb)) && ta[prefix] == tb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro)
+}
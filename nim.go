@@ -0,0 +1,202 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// nimRoutine matches the start of a routine declaration up to its
+// (exported) name, eg: "proc `$`*" or `func add`
+var nimRoutine = regexp.MustCompile("(?m)^[ \\t]*((?:proc|func|method|iterator|converter)[ \\t]+)(`[^`\\n]+`|[A-Za-z_]\\w*)[ \\t]*\\*?")
+
+// nimPragma matches pragmas like `{.closure.}`
+var nimPragma = regexp.MustCompile(`\{\.[^}]*\}`)
+
+// parseNim finds proc, func, method, iterator and converter
+// declarations in Nim source. There is no tree-sitter grammar for Nim
+// available to us, so declarations are found line by line, skipping
+// generic parameters in brackets. Parameters without a type (but with
+// a default value) and routines without a return type use `_`.
+func parseNim(sourceCode []byte, path string) []Func {
+	funcs := []Func{}
+	src := blankCommentsAndStrings(string(sourceCode), nimSyntax)
+	lines := strings.Split(string(sourceCode), "\n")
+
+	for _, m := range nimRoutine.FindAllStringSubmatchIndex(src, -1) {
+		i := m[1]
+		skip := func() {
+			for i < len(src) && (src[i] == ' ' || src[i] == '\t') {
+				i++
+			}
+		}
+
+		// generic parameters
+		skip()
+		if i < len(src) && src[i] == '[' {
+			end := matchingBracket(src, i)
+			if end == -1 {
+				continue
+			}
+			i = end + 1
+		}
+
+		f := Func{
+			Path: path,
+			Loc:  position(sourceCode, m[2]),
+			Name: strings.Trim(src[m[4]:m[5]], "`"),
+			Args: []string{},
+			Rets: []string{"_"},
+		}
+		f.Doc = nimDoc(lines, f.Loc[0])
+
+		skip()
+		if i < len(src) && src[i] == '(' {
+			end := matchingBracket(src, i)
+			if end == -1 {
+				continue
+			}
+			f.Args = nimParams(src[i+1 : end])
+			i = end + 1
+		}
+
+		skip()
+		if i < len(src) && src[i] == ':' {
+			if ret := strings.Join(strings.Fields(src[i+1:i+1+nimTypeEnd(src[i+1:])]), " "); ret != "" {
+				f.Rets = []string{ret}
+			}
+		}
+
+		// forward declarations have no `=` after the parameters
+		rest := src[i:]
+		if nl := strings.IndexByte(rest, '\n'); nl != -1 {
+			rest = rest[:nl]
+		}
+		f.BodyLoc = nimBody(lines, f.Loc[0], !strings.Contains(rest, "="))
+
+		funcs = append(funcs, f)
+	}
+
+	return funcs
+}
+
+// nimDoc is the doc comment of the routine declared on row of lines,
+// which comes right after the declaration in Nim
+func nimDoc(lines []string, row int) string {
+	end := row + 1
+	for end < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[end]), "##") {
+		end++
+	}
+	return cleanComment(strings.Join(lines[row+1:end], "\n"))
+}
+
+// nimBody is the first and last rows of the routine declared on row of
+// lines: its declaration and the lines after it that are indented more.
+// Forward declarations have no body.
+func nimBody(lines []string, row int, forward bool) []int {
+	indent := len(lines[row]) - len(strings.TrimLeft(lines[row], " \t"))
+	last := row
+	for j := row + 1; j < len(lines); j++ {
+		text := strings.TrimLeft(lines[j], " \t")
+		if text == "" {
+			continue
+		}
+		if len(lines[j])-len(text) <= indent {
+			break
+		}
+		last = j
+	}
+	if last == row && forward {
+		return nil
+	}
+	return []int{row, last}
+}
+
+// nimParams returns the types of the parameters in text, eg:
+// `a, b: int; c = 3` is int, int and _
+func nimParams(text string) []string {
+	types := []string{}
+	pending := 0 // names waiting for the type of the next parameter
+
+	for _, group := range splitTopLevel(text, ";") {
+		for _, p := range splitTopLevel(group, ",") {
+			p = strings.TrimSpace(p)
+			if p == "" {
+				continue
+			}
+
+			t, typed := "_", false
+			if parts := splitTopLevel(p, ":"); len(parts) > 1 {
+				t = splitTopLevel(strings.Join(parts[1:], ":"), "=")[0]
+				typed = true
+			}
+			defaulted := len(splitTopLevel(p, "=")) > 1
+
+			if !typed && !defaulted {
+				pending++
+				continue
+			}
+
+			t = strings.Join(strings.Fields(nimPragma.ReplaceAllString(t, "")), " ")
+			for ; pending >= 0; pending-- {
+				types = append(types, t)
+			}
+			pending = 0
+		}
+	}
+
+	// names without a type at the end
+	for ; pending > 0; pending-- {
+		types = append(types, "_")
+	}
+
+	return types
+}
+
+// nimTypeEnd returns the offset where the return type at the start of s
+// ends, which is at a top level `=`, a pragma (`{.`) or the end of the line
+func nimTypeEnd(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case '{':
+			if strings.HasPrefix(s[i:], "{.") {
+				return i
+			}
+			depth++
+		case '}':
+			depth--
+		case '=':
+			if depth == 0 && !strings.HasPrefix(s[i:], "==") {
+				return i
+			}
+		case '\n':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(s)
+}
+
+// nimSyntax are the comments and literals of Nim, raw and triple
+// quoted strings have no escapes and `'` after a number is a type
+// suffix (1'u8)
+var nimSyntax = lexicalSyntax{
+	lineComments:  []string{"#"},
+	blockComments: [][2]string{{"#[", "]#"}},
+	nested:        true,
+	quotes: []quoteSyntax{
+		{open: `"""`, close: `"""`},
+		{open: `r"`, close: `"`},
+		{open: `R"`, close: `"`},
+		{open: `"`, close: `"`, escape: true},
+		{open: "'", close: "'", escape: true, oneLine: true, notAfter: func(prev byte) bool {
+			return prev == '_' || unicode.IsLetter(rune(prev)) || unicode.IsDigit(rune(prev))
+		}},
+	},
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// typeAliases maps the names that languages use for the same builtin
+// type onto a single name so that a query like `( string, int ) -> ( bool )`
+// finds functions in all of them
+var typeAliases = map[string]string{
+	// strings
+	"String":           "string",
+	"str":              "string",
+	"std::string":      "string",
+	"java.lang.String": "string",
+	"System.String":    "string",
+	"NSString":         "string",
+	"string()":         "string",
+	"binary()":         "string",
+
+	// characters
+	"Char":      "char",
+	"Character": "char",
+	"rune":      "char",
+
+	// integers
+	"Int":       "int",
+	"Integer":   "int",
+	"integer":   "int",
+	"integer()": "int",
+	"isize":     "int",
+
+	"i8":     "int8",
+	"Int8":   "int8",
+	"int8_t": "int8",
+	"sbyte":  "int8",
+
+	"i16":     "int16",
+	"Int16":   "int16",
+	"int16_t": "int16",
+	"short":   "int16",
+	"Short":   "int16",
+
+	"i32":          "int32",
+	"Int32":        "int32",
+	"int32_t":      "int32",
+	"System.Int32": "int32",
+
+	"i64":          "int64",
+	"Int64":        "int64",
+	"int64_t":      "int64",
+	"long":         "int64",
+	"Long":         "int64",
+	"System.Int64": "int64",
+
+	"u8":      "byte",
+	"uint8":   "byte",
+	"UInt8":   "byte",
+	"uint8_t": "byte",
+	"Byte":    "byte",
+
+	"u16":      "uint16",
+	"UInt16":   "uint16",
+	"uint16_t": "uint16",
+	"ushort":   "uint16",
+
+	"u32":      "uint32",
+	"UInt32":   "uint32",
+	"uint32_t": "uint32",
+
+	"u64":      "uint64",
+	"UInt64":   "uint64",
+	"uint64_t": "uint64",
+	"ulong":    "uint64",
+
+	"usize":  "uint",
+	"size_t": "uint",
+	"UInt":   "uint",
+
+	// floats
+	"f32":     "float32",
+	"float":   "float32",
+	"Float":   "float32",
+	"Float32": "float32",
+
+	"f64":     "float64",
+	"double":  "float64",
+	"Double":  "float64",
+	"Float64": "float64",
+	"float()": "float64",
+
+	// booleans
+	"Bool":      "bool",
+	"boolean":   "bool",
+	"Boolean":   "bool",
+	"boolean()": "bool",
+
+	// no value
+	"Void": "void",
+	"Unit": "void",
+	"unit": "void",
+
+	// anything
+	"Any":       "any",
+	"Object":    "any",
+	"object":    "any",
+	"mixed":     "any",
+	"dynamic":   "any",
+	"term()":    "any",
+	"any()":     "any",
+	"AnyObject": "any",
+}
+
+// typeAliasPhrases are aliases spanning more than a single name, they
+// are replaced (in order) before typeAliases
+var typeAliasPhrases = [][]string{
+	{"unsigned long long", "uint64"},
+	{"unsigned long", "uint64"},
+	{"unsigned int", "uint32"},
+	{"unsigned short", "uint16"},
+	{"unsigned char", "byte"},
+	{"long long", "int64"},
+	{"long double", "float64"},
+	{"interface{}", "any"},
+}
+
+// typeName matches a (possibly qualified) type name, eg: `std::string`
+// or `integer()`
+var typeName = regexp.MustCompile(`[A-Za-z_]\w*(?:(?:::|\.)[A-Za-z_]\w*)*(?:\(\))?`)
+
+// normalizeType replaces the builtin type names in t with the names
+// in typeAliases
+func normalizeType(t string) string {
+	for _, p := range typeAliasPhrases {
+		t = strings.ReplaceAll(t, p[0], p[1])
+	}
+
+	return typeName.ReplaceAllStringFunc(t, func(name string) string {
+		if alias, ok := typeAliases[name]; ok {
+			return alias
+		}
+		return name
+	})
+}
+
+// Normalized returns f with the types of its arguments and results
+// normalized using normalizeType
+func (f Func) Normalized() Func {
+	args := make([]string, len(f.Args))
+	for i, a := range f.Args {
+		args[i] = normalizeType(a)
+	}
+
+	rets := make([]string, len(f.Rets))
+	for i, r := range f.Rets {
+		rets[i] = normalizeType(r)
+	}
+
+	f.Args, f.Rets = args, rets
+	return f
+}
@@ -0,0 +1,437 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// output writes results in one of the output formats
+type output interface {
+	// write writes a single result
+	write(f FuncWithDistance) error
+	// close writes anything that has to come after the results
+	close() error
+}
+
+var outputFormats = map[string]func(w io.Writer, po printOptions) output{
+	"text":     func(w io.Writer, po printOptions) output { return &textOutput{w: w, po: po} },
+	"jsonl":    newJSONLOutput,
+	"grep":     func(w io.Writer, po printOptions) output { return grepOutput{w, po, ""} },
+	"emacs":    func(w io.Writer, po printOptions) output { return grepOutput{w, po, "info: "} },
+	"csv":      func(w io.Writer, po printOptions) output { return newCSVOutput(w, ',') },
+	"tsv":      func(w io.Writer, po printOptions) output { return newCSVOutput(w, '\t') },
+	"markdown": func(w io.Writer, po printOptions) output { return &markdownOutput{w: w} },
+	"sarif":    func(w io.Writer, po printOptions) output { return &sarifOutput{w: w} },
+}
+
+// getOutput returns the output format po.Format (or po.Template if
+// set) writing to w
+func getOutput(po printOptions, w io.Writer) (output, error) {
+	if po.Print0 {
+		return &print0Output{w: w, seen: map[string]bool{}}, nil
+	}
+	if po.Template != "" {
+		return newTemplateOutput(w, po.Template)
+	}
+
+	o, ok := outputFormats[po.Format]
+	if !ok {
+		return nil, fmt.Errorf("invalid output format '%s' (options: %s)", po.Format, strings.Join(outputNames(), ", "))
+	}
+	return o(w, po), nil
+}
+
+func outputNames() []string {
+	names := []string{}
+	for n := range outputFormats {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// printOptions configures which results are printed and how
+type printOptions struct {
+	Format    string
+	Template  string          // Go template to print every result with instead of Format
+	Print0    bool            // only print the paths, ending with a NUL
+	Threshold float64         // maximum distance of the results to show
+	Limit     int             // maximum number of results to show, all if negative
+	NullFile  bool            // end paths with a NUL instead of a `:` with -o grep and emacs
+	Color     bool            // color the text output
+	Highlight map[string]bool // names to highlight in the signatures with Color
+	ShowDoc   bool            // show the first line of the docs with the text, grep and emacs formats
+	ShowScore bool            // show the distances with the text, grep and emacs formats
+	Explain   bool            // show the parts of the distances with the text, grep and emacs formats
+	ShowBody  int             // lines of the bodies to show with the text format
+	Context   int             // lines of source to show around the functions with the text format
+	Link      *linker         // turn the locations into hyperlinks with the text format, if set
+	SortBy    string          // show the results by score, path or name
+	GroupBy   string          // keep the results in the same file, package or language together, with a header in the text format
+}
+
+// printResults prints the closest (up to po.Limit) functions that are
+// at most po.Threshold away from the query, sorted by po.SortBy and
+// grouped by po.GroupBy
+func printResults(fwd []FuncWithDistance, po printOptions, w io.Writer) error {
+	out, err := getOutput(po, w)
+	if err != nil {
+		return err
+	}
+
+	shown := []FuncWithDistance{}
+	for i, f := range fwd {
+		if f.Distance > po.Threshold || (po.Limit >= 0 && i >= po.Limit) {
+			break
+		}
+		shown = append(shown, f)
+	}
+
+	for _, f := range groupResults(sortResults(shown, po.SortBy), po.GroupBy) {
+		if err := out.write(f); err != nil {
+			return err
+		}
+	}
+
+	return out.close()
+}
+
+// hasResults checks if any of fwd is shown with po
+func hasResults(fwd []FuncWithDistance, po printOptions) bool {
+	if po.Limit == 0 {
+		return false
+	}
+	for _, f := range fwd {
+		if f.Distance <= po.Threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// streamResults returns a function that prints the results that are at
+// most po.Threshold away from the query as soon as they are scored, in
+// no particular order
+func streamResults(out output, po printOptions) func(FuncWithDistance) error {
+	seen := map[string]bool{}
+	return func(f FuncWithDistance) error {
+		key := fmt.Sprintf("%s:%v", f.Func.Path, f.Func.Loc)
+		if f.Distance > po.Threshold || seen[key] {
+			return nil
+		}
+		seen[key] = true
+		return out.write(f)
+	}
+}
+
+// result is a result in machine readable formats, with 1 based lines
+// and columns. The end is where the declaration ends (exclusive), if
+// known.
+type result struct {
+	Path      string   `json:"path"`
+	Line      int      `json:"line"`
+	Column    int      `json:"column"`
+	Offset    int      `json:"offset"`
+	EndLine   int      `json:"end_line,omitempty"`
+	EndColumn int      `json:"end_column,omitempty"`
+	Name      string   `json:"name"`
+	Receiver  string   `json:"receiver,omitempty"`
+	Args      []string `json:"args"`
+	Labels    []string `json:"labels,omitempty"`
+	Params    []string `json:"params,omitempty"`
+	Rets      []string `json:"rets"`
+	Signature string   `json:"signature"`
+	Doc       string   `json:"doc,omitempty"`
+	Distance  float64  `json:"distance"`
+	Query     string   `json:"query,omitempty"`
+}
+
+func newResult(f FuncWithDistance) result {
+	r := result{
+		Path:      f.Func.Path,
+		Line:      f.Func.Loc[0] + 1,
+		Column:    f.Func.Loc[1] + 1,
+		Name:      f.Func.Name,
+		Receiver:  f.Func.Receiver,
+		Args:      f.Func.Args,
+		Labels:    f.Func.Labels,
+		Params:    f.Func.Params,
+		Rets:      f.Func.Rets,
+		Signature: f.Func.LabelledSignature(),
+		Doc:       f.Func.Doc,
+		Distance:  f.Distance,
+		Query:     f.Query,
+	}
+	if loc := f.Func.Loc; len(loc) >= 3 {
+		r.Offset = loc[2]
+	}
+	if loc := f.Func.Loc; len(loc) >= 5 {
+		r.EndLine, r.EndColumn = loc[3]+1, loc[4]+1
+	}
+	return r
+}
+
+// textOutput is the default `path:row:col:name (args) -> (rets)`
+// format, followed by the annotations (eg: the doc) and by the query
+// that matched if there were several. The first po.ShowBody lines of the
+// body and po.Context lines around the function are shown indented
+// below it. With po.GroupBy, every group starts with a header and with
+// po.Link the locations are links.
+type textOutput struct {
+	w     io.Writer
+	po    printOptions
+	group *string
+}
+
+func (o *textOutput) write(f FuncWithDistance) error {
+	if group, ok := groupings[o.po.GroupBy]; ok {
+		if key := group(f.Func); o.group == nil || *o.group != key {
+			header := key
+			if o.po.Color {
+				header = colorName + header + colorReset
+			}
+			if o.group != nil {
+				header = "\n" + header
+			}
+			if _, err := fmt.Fprintln(o.w, header); err != nil {
+				return err
+			}
+			o.group = &key
+		}
+	}
+
+	location, description := fmt.Sprintf("%s:%d:%d", f.Func.Path, f.Func.Loc[0], f.Func.Loc[1]), describe(f.Func)
+	if o.po.Color {
+		location, description = colorize(f.Func, o.po.Highlight)
+	}
+	if o.po.Link != nil {
+		url, err := o.po.Link.url(f)
+		if err != nil {
+			return err
+		}
+		location = hyperlink(url, location)
+	}
+	line := location + ":" + description
+	if a := annotations(f, o.po); a != "" {
+		if o.po.Color {
+			a = colorDoc + a + colorReset
+		}
+		line += a
+	}
+	if f.Query != "" {
+		line += "\t" + f.Query
+	}
+	body, err := bodySnippet(f.Func, o.po.ShowBody)
+	if err != nil {
+		return err
+	}
+	for _, l := range body {
+		line += "\n    " + l
+	}
+	if o.po.Context > 0 {
+		lines, err := contextLines(f.Func.Path, f.Func.Loc[0], o.po.Context)
+		if err != nil {
+			return err
+		}
+		for _, l := range lines {
+			line += "\n    " + l
+		}
+	}
+	_, err = fmt.Fprintln(o.w, line)
+	return err
+}
+
+func (o *textOutput) close() error { return nil }
+
+// jsonlOutput writes a JSON object per result
+type jsonlOutput struct{ enc *json.Encoder }
+
+func newJSONLOutput(w io.Writer, po printOptions) output {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false) // keep `->` readable
+	return jsonlOutput{enc}
+}
+
+func (o jsonlOutput) write(f FuncWithDistance) error { return o.enc.Encode(newResult(f)) }
+
+func (o jsonlOutput) close() error { return nil }
+
+// grepOutput is `path:line:col: name (args) -> (rets)` with 1 based
+// lines and columns, as understood by vim's quickfix and most editors.
+// The emacs format adds an `info:` severity so that compilation-mode
+// does not show the results as errors. With nullFile, the path is
+// followed by a NUL instead (like `grep --null`) so that paths with a
+// `:` (eg: from TRAMP) can be parsed. The annotations (eg: the doc)
+// follow the signature.
+type grepOutput struct {
+	w        io.Writer
+	po       printOptions
+	severity string
+}
+
+func (o grepOutput) write(f FuncWithDistance) error {
+	r := newResult(f)
+	sep := ":"
+	if o.po.NullFile {
+		sep = "\x00"
+	}
+	line := fmt.Sprintf("%s%s%d:%d: %s%s", r.Path, sep, r.Line, r.Column, o.severity, describe(f.Func))
+	line += annotations(f, o.po)
+	if f.Query != "" {
+		line += "\t" + f.Query
+	}
+	_, err := fmt.Fprintln(o.w, line)
+	return err
+}
+
+func (o grepOutput) close() error { return nil }
+
+// annotations is what is shown after the signature of f with po.ShowScore,
+// po.Explain and po.ShowDoc, eg: `  [3 = name 0 + args 2 + rets 0 + arity 1]  // Parse parses`
+func annotations(f FuncWithDistance, po printOptions) string {
+	a := ""
+	if po.Explain && f.Score != nil {
+		a += fmt.Sprintf("  [%g = name %g + args %g + rets %g + arity %g]", f.Distance, f.Score.Name, f.Score.Args, f.Score.Rets, f.Score.Arity)
+	} else if po.ShowScore || po.Explain {
+		a += fmt.Sprintf("  [%g]", f.Distance)
+	}
+	if doc := docSummary(f.Func); po.ShowDoc && doc != "" {
+		a += "  // " + doc
+	}
+	return a
+}
+
+// describe is the name and signature of f, eg: `parse (string) -> (int, error)`
+func describe(f Func) string {
+	return fmt.Sprintf("%s (%s) -> (%s)", f.FullName(), strings.Join(f.LabelledArgs(), ", "), strings.Join(f.Rets, ", "))
+}
+
+// templateOutput executes a Go template (like
+// `{{.Path}}:{{.Line}} {{.Name}}{{.Signature}}`) with the result of
+// every function, followed by a newline
+type templateOutput struct {
+	w    io.Writer
+	tmpl *template.Template
+}
+
+func newTemplateOutput(w io.Writer, format string) (output, error) {
+	tmpl, err := template.New("format").Funcs(template.FuncMap{"join": strings.Join}).Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid format: %w", err)
+	}
+	return templateOutput{w, tmpl}, nil
+}
+
+func (o templateOutput) write(f FuncWithDistance) error {
+	if err := o.tmpl.Execute(o.w, newResult(f)); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(o.w)
+	return err
+}
+
+func (o templateOutput) close() error { return nil }
+
+// print0Output writes the path of every result once, ending with a NUL
+// like `find -print0` (for `xargs -0`)
+type print0Output struct {
+	w    io.Writer
+	seen map[string]bool
+}
+
+func (o *print0Output) write(f FuncWithDistance) error {
+	if o.seen[f.Func.Path] {
+		return nil
+	}
+	o.seen[f.Func.Path] = true
+	_, err := fmt.Fprintf(o.w, "%s\x00", f.Func.Path)
+	return err
+}
+
+func (o *print0Output) close() error { return nil }
+
+// csvOutput writes a header and a row per result, with the arguments
+// and results separated by `, ` (and quoted if needed)
+type csvOutput struct{ w *csv.Writer }
+
+func newCSVOutput(w io.Writer, comma rune) output {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	cw.Write([]string{"path", "line", "column", "name", "args", "rets", "distance", "query", "offset", "end_line", "end_column"})
+	return csvOutput{cw}
+}
+
+func (o csvOutput) write(f FuncWithDistance) error {
+	r := newResult(f)
+	return o.w.Write([]string{
+		r.Path,
+		strconv.Itoa(r.Line),
+		strconv.Itoa(r.Column),
+		f.Func.FullName(),
+		strings.Join(f.Func.LabelledArgs(), ", "),
+		strings.Join(r.Rets, ", "),
+		strconv.FormatFloat(r.Distance, 'g', -1, 64),
+		r.Query,
+		strconv.Itoa(r.Offset),
+		optionalInt(r.EndLine),
+		optionalInt(r.EndColumn),
+	})
+}
+
+// optionalInt is n, or empty if it is 0 (unknown)
+func optionalInt(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func (o csvOutput) close() error {
+	o.w.Flush()
+	return o.w.Error()
+}
+
+// markdownOutput writes a table of the results, with a column for the
+// query if there were several
+type markdownOutput struct {
+	w      io.Writer
+	header bool
+}
+
+func (o *markdownOutput) write(f FuncWithDistance) error {
+	cell := func(s string) string { return strings.ReplaceAll(s, "|", `\|`) }
+	code := func(s string) string { return "`" + cell(s) + "`" }
+
+	r := newResult(f)
+	row := []string{
+		code(f.Func.FullName()),
+		code(fmt.Sprintf("(%s) -> (%s)", strings.Join(f.Func.LabelledArgs(), ", "), strings.Join(r.Rets, ", "))),
+		cell(fmt.Sprintf("%s:%d", r.Path, r.Line)),
+		strconv.FormatFloat(r.Distance, 'g', 4, 64),
+	}
+	if f.Query != "" {
+		row = append(row, code(f.Query))
+	}
+
+	if !o.header {
+		o.header = true
+		header := []string{"Name", "Signature", "Location", "Score"}
+		if f.Query != "" {
+			header = append(header, "Query")
+		}
+		if _, err := fmt.Fprintf(o.w, "| %s |\n|%s\n", strings.Join(header, " | "), strings.Repeat(" --- |", len(header))); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(o.w, "| %s |\n", strings.Join(row, " | "))
+	return err
+}
+
+func (o *markdownOutput) close() error { return nil }
@@ -0,0 +1,188 @@
+package main
+
+/*
+#cgo LDFLAGS: -ldl
+#include <dlfcn.h>
+#include <stdlib.h>
+
+typedef const void *(*language_func)(void);
+
+static const void *load_language(const char *path, const char *symbol, char **err) {
+	void *lib = dlopen(path, RTLD_NOW | RTLD_LOCAL);
+	if (lib == NULL) {
+		*err = dlerror();
+		return NULL;
+	}
+
+	language_func fn = (language_func)dlsym(lib, symbol);
+	if (fn == NULL) {
+		*err = dlerror();
+		return NULL;
+	}
+
+	return fn();
+}
+*/
+import "C"
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// ABI versions of compiled grammars that the bundled tree-sitter
+// runtime can use
+const (
+	minGrammarVersion = 13
+	maxGrammarVersion = 14
+)
+
+// grammarConfig describes an external grammar. It is read from a json
+// file in the grammars directory, eg: `nim.json`:
+//
+//	{
+//	  "library": "nim.so",
+//	  "extensions": [".nim"],
+//	  "queries": {
+//	    "function": "(proc_declaration name: (_) @name) @func",
+//	    "input": "(parameter_declaration type: (_) @type)",
+//	    "output": "(proc_declaration return_type: (_) @type)"
+//	  }
+//	}
+//
+// The name of the language defaults to the name of the file and the
+// library (relative to the config file) has to export the function
+// `tree_sitter_<name>` unless Symbol is set. Queries are the same as
+// for the builtin languages. Like them, files can also be matched by
+// "filenames", "interpreters" (shebang) and "aliases" (modeline).
+type grammarConfig struct {
+	Name         string            `json:"name"`
+	Library      string            `json:"library"`
+	Symbol       string            `json:"symbol"`
+	Extensions   []string          `json:"extensions"`
+	Filenames    []string          `json:"filenames"`
+	Interpreters []string          `json:"interpreters"`
+	Aliases      []string          `json:"aliases"`
+	Queries      map[string]string `json:"queries"`
+}
+
+// defaultGrammarsDir is where external grammars are loaded from if
+// -grammars is not set
+func defaultGrammarsDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "glee", "grammars")
+}
+
+// loadGrammars adds the external grammars configured in dir to
+// languages, replacing builtin languages of the same name. A missing
+// directory is not an error.
+func loadGrammars(dir string) error {
+	if dir == "" {
+		return nil
+	}
+
+	configs, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range configs {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var g grammarConfig
+		if err := json.Unmarshal(content, &g); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+
+		if g.Name == "" {
+			g.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+		}
+		if g.Symbol == "" {
+			g.Symbol = "tree_sitter_" + strings.ReplaceAll(g.Name, "-", "_")
+		}
+
+		switch {
+		case g.Library == "":
+			return fmt.Errorf("%s: no library", path)
+		case strings.HasSuffix(g.Library, ".wasm"):
+			return fmt.Errorf("%s: wasm grammars are not supported, use a shared library", path)
+		case len(g.Extensions) == 0 && len(g.Filenames) == 0 && len(g.Interpreters) == 0:
+			return fmt.Errorf("%s: no extensions, filenames or interpreters", path)
+		case g.Queries["function"] == "":
+			return fmt.Errorf("%s: no function query", path)
+		}
+
+		library := g.Library
+		if !filepath.IsAbs(library) {
+			library = filepath.Join(dir, library)
+		}
+
+		languages[g.Name] = language{
+			Version:      grammarVersion(library, content),
+			Extensions:   g.Extensions,
+			Filenames:    g.Filenames,
+			Interpreters: g.Interpreters,
+			Aliases:      g.Aliases,
+			Grammar:      externalGrammar(library, g.Symbol),
+			Queries:      g.Queries,
+		}
+	}
+
+	return nil
+}
+
+// grammarVersion identifies the library at path and the config of an
+// external grammar, so that the functions parsed with another build of
+// the grammar or other queries are parsed again
+func grammarVersion(path string, config []byte) string {
+	version := fmt.Sprintf("%s %x", path, sha256.Sum256(config))
+	if info, err := os.Stat(path); err == nil {
+		version += fmt.Sprintf(" %d %d", info.ModTime().UnixNano(), info.Size())
+	}
+	return version
+}
+
+// externalGrammar returns a Grammar that loads the grammar exported as
+// symbol from the shared library at path the first time it is used
+func externalGrammar(path, symbol string) func() *sitter.Language {
+	var once sync.Once
+	var lang *sitter.Language
+
+	return func() *sitter.Language {
+		once.Do(func() {
+			cpath, csymbol := C.CString(path), C.CString(symbol)
+			defer C.free(unsafe.Pointer(cpath))
+			defer C.free(unsafe.Pointer(csymbol))
+
+			var cerr *C.char
+			ptr := C.load_language(cpath, csymbol, &cerr)
+			if ptr == nil {
+				fatalf("unable to load grammar %s from %s: %s", symbol, path, C.GoString(cerr))
+			}
+
+			// the ABI version is the first field of TSLanguage
+			version := *(*uint32)(ptr)
+			if version < minGrammarVersion || version > maxGrammarVersion {
+				fatalf("grammar %s uses ABI version %d, only %d to %d are supported",
+					path, version, minGrammarVersion, maxGrammarVersion)
+			}
+
+			lang = sitter.NewLanguage(unsafe.Pointer(ptr))
+		})
+		return lang
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadGrammars(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		err    string // part of the error, if loading has to fail
+	}{
+		{"shared library", `{"library": "toy.so", "extensions": [".toy"], "queries": {"function": "(proc) @func"}}`, ""},
+		{"invalid json", `{"library": `, "toy.json"},
+		{"no library", `{"extensions": [".toy"], "queries": {"function": "(proc) @func"}}`, "no library"},
+		{"wasm", `{"library": "toy.wasm", "extensions": [".toy"], "queries": {"function": "(proc) @func"}}`, "wasm grammars are not supported"},
+		{"no files", `{"library": "toy.so", "queries": {"function": "(proc) @func"}}`, "no extensions, filenames or interpreters"},
+		{"no function query", `{"library": "toy.so", "extensions": [".toy"], "queries": {}}`, "no function query"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "toy.json"), []byte(tt.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			defer delete(languages, "toy")
+
+			err := loadGrammars(dir)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				// the library is only opened when a file of the language is parsed
+				if l, ok := languages["toy"]; !ok || l.Grammar == nil || !strings.Contains(l.Version, filepath.Join(dir, "toy.so")) {
+					t.Errorf("loadGrammars did not add the language: %+v", l)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("loadGrammars() error = %v, want %q", err, tt.err)
+			}
+			if _, ok := languages["toy"]; ok {
+				t.Error("loadGrammars added a language with an invalid config")
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// progressInterval is how often the progress line is redrawn
+const progressInterval = 100 * time.Millisecond
+
+// progress shows how many of the files have been parsed on a line of
+// stderr, eg: `120/530 files, 2451 functions (340 files/s)`. Nothing is
+// shown when stderr is not a terminal so that logs stay readable.
+type progress struct {
+	w     io.Writer // nil if nothing is shown
+	total int
+	files int
+	funcs int
+	start time.Time
+	drawn time.Time
+}
+
+func newProgress(total int, quiet bool) *progress {
+	p := &progress{total: total, start: time.Now()}
+	if !quiet && stderrIsTerminal() {
+		p.w = os.Stderr
+	}
+	return p
+}
+
+// add counts a parsed file with funcs functions
+func (p *progress) add(funcs int) {
+	p.files++
+	p.funcs += funcs
+	if p.w == nil || (time.Since(p.drawn) < progressInterval && p.files < p.total) {
+		return
+	}
+
+	p.drawn = time.Now()
+	rate := float64(p.files) / p.drawn.Sub(p.start).Seconds()
+	fmt.Fprintf(p.w, "%s%d/%d files, %d functions (%.0f files/s)\r", LINE_CLEAR, p.files, p.total, p.funcs, rate)
+}
+
+// done clears the progress line
+func (p *progress) done() {
+	if p.w != nil {
+		fmt.Fprint(p.w, LINE_CLEAR)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// promptQuery asks for the name, the arguments and the results of the
+// functions to search for on in and builds a query from them, for when
+// glee is run in a terminal without one. The query is shown so that it
+// can be passed directly the next time.
+func promptQuery(in io.Reader) (string, error) {
+	scanner := bufio.NewScanner(in)
+	ask := func(question string) (string, error) {
+		fmt.Fprintf(os.Stderr, "%s: ", question)
+		if !scanner.Scan() {
+			fmt.Fprintln(os.Stderr)
+			if err := scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", fmt.Errorf("no query")
+		}
+		return strings.TrimSpace(scanner.Text()), nil
+	}
+
+	name, err := ask("name (optional)")
+	if err != nil {
+		return "", err
+	}
+	args, err := ask("arguments (comma separated, eg: string, int)")
+	if err != nil {
+		return "", err
+	}
+	rets, err := ask("results (comma separated, eg: error)")
+	if err != nil {
+		return "", err
+	}
+
+	query := fmt.Sprintf("( %s ) -> ( %s )", args, rets)
+	if name != "" {
+		query = name + " : " + query
+	}
+	fmt.Fprintf(os.Stderr, "searching for '%s'\n", query)
+	return query, nil
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// refine reads constraints from in after the results have been shown
+// and prints the results again, keeping only the functions that take
+// or return the types added with `+T` and none of the ones added with
+// `-T` (eg: `+context.Context -error`). `clear` drops all of them and
+// an empty line (or EOF) stops. Only the already ranked results are
+// filtered, the files are not searched again.
+func refine(in io.Reader, fwd []FuncWithDistance, opts searchOptions, po printOptions) error {
+	required, excluded := []string{}, []string{}
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprintf(os.Stderr, "%s> ", strings.Join(append([]string{"refine"}, constraintsString(required, excluded)...), " "))
+		if !scanner.Scan() {
+			fmt.Fprintln(os.Stderr)
+			return scanner.Err()
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			return nil
+		}
+
+		if line == "clear" {
+			required, excluded = []string{}, []string{}
+		} else {
+			r, e, err := opts.addConstraints(required, excluded, strings.Fields(line))
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+			required, excluded = r, e
+		}
+
+		refined := []FuncWithDistance{}
+		for _, f := range fwd {
+			types := opts.types(f.Func)
+			if contains(types, required, 0) && !containsAny(types, excluded) {
+				refined = append(refined, f)
+			}
+		}
+		if err := printResults(refined, po, os.Stdout); err != nil {
+			return err
+		}
+	}
+}
+
+// addConstraints adds constraints like `+T` and `-T` to the required
+// and excluded types, where the last one for a type wins
+func (opts searchOptions) addConstraints(required, excluded, constraints []string) ([]string, []string, error) {
+	for _, c := range constraints {
+		t := opts.prepareType(c[1:])
+		switch {
+		case t == "":
+			return nil, nil, fmt.Errorf("invalid constraint '%s', expected +type or -type", c)
+		case c[0] == '+':
+			required, excluded = append(remove(required, t), t), remove(excluded, t)
+		case c[0] == '-':
+			required, excluded = remove(required, t), append(remove(excluded, t), t)
+		default:
+			return nil, nil, fmt.Errorf("invalid constraint '%s', expected +type or -type", c)
+		}
+	}
+	return required, excluded, nil
+}
+
+// prepareType applies the options to a type in a constraint like they
+// would be applied to the query
+func (opts searchOptions) prepareType(t string) string {
+	t = expandQueryAliases(t)
+	if opts.Normalize {
+		t = normalizeType(t)
+	}
+	if prepared := opts.prepareQuery([]string{t}); len(prepared) == 1 {
+		return prepared[0]
+	}
+	return ""
+}
+
+// types returns the (labelled) arguments and the results of f, with
+// the options applied
+func (opts searchOptions) types(f Func) []string {
+	nf := opts.prepare(f, nil, nil, false)
+	return append(append(nf.LabelledArgs(), nf.Args...), nf.Rets...)
+}
+
+// containsAny checks if any of the tests is exactly one of the items,
+// so that excluding `int` does not drop functions that take a `Point`.
+// Wildcards are not any type here and never match.
+func containsAny(items []string, tests []string) bool {
+	for _, test := range tests {
+		if isWildcard(test) {
+			continue
+		}
+		for _, item := range items {
+			if item == test {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func remove(items []string, item string) []string {
+	kept := []string{}
+	for _, i := range items {
+		if i != item {
+			kept = append(kept, i)
+		}
+	}
+	return kept
+}
+
+func constraintsString(required, excluded []string) []string {
+	constraints := []string{}
+	for _, t := range required {
+		constraints = append(constraints, "+"+t)
+	}
+	for _, t := range excluded {
+		constraints = append(constraints, "-"+t)
+	}
+	return constraints
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// getRegexInputsAndOutput splits a query like `( \*?os\.File, .* ) -> ( error )`
+// into the regular expressions of its inputs and outputs. Unlike
+// getInputsAndOutput, parens and commas within the expressions are
+// kept.
+func getRegexInputsAndOutput(uinput string) ([]string, []string, error) {
+	parts := splitTopLevel(uinput, "->")
+	if len(parts) != 2 {
+		return nil, nil, fmt.Errorf("invalid input")
+	}
+
+	// a missing side (`( .* ) ->`) matches anything
+	slots := func(s string) []string {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return nil
+		}
+		if strings.HasPrefix(s, "(") && matchingBracket(s, 0) == len(s)-1 {
+			s = s[1 : len(s)-1]
+		}
+
+		res := []string{}
+		for _, p := range splitTopLevel(s, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				res = append(res, p)
+			}
+		}
+		return res
+	}
+
+	return slots(parts[0]), slots(parts[1]), nil
+}
+
+// filterRegex keeps the functions where every input and output matches
+// the regular expression at the same position in the query. The
+// expressions have to match the whole type and `_` matches any type.
+func filterRegex(funcs []Func, inputs, outputs []string, opts searchOptions) ([]Func, error) {
+	flags := ""
+	if opts.IgnoreCase {
+		flags = "(?i)"
+	}
+
+	compile := func(res []string) ([]*regexp.Regexp, error) {
+		if res == nil {
+			return nil, nil
+		}
+
+		compiled := []*regexp.Regexp{}
+		for _, re := range res {
+			if isWildcard(re) {
+				re = ".*"
+			}
+
+			r, err := regexp.Compile(flags + `^(?:` + re + `)$`)
+			if err != nil {
+				return nil, err
+			}
+			compiled = append(compiled, r)
+		}
+		return compiled, nil
+	}
+
+	in, err := compile(inputs)
+	if err != nil {
+		return nil, err
+	}
+	out, err := compile(outputs)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := func(types []string, res []*regexp.Regexp) bool {
+		if res == nil {
+			return true
+		}
+		if len(types) != len(res) {
+			return false
+		}
+		for i, r := range res {
+			if !r.MatchString(types[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	filtered := []Func{}
+	for _, f := range funcs {
+		if matches(f.Args, in) && matches(f.Rets, out) {
+			filtered = append(filtered, f)
+		}
+	}
+
+	return filtered, nil
+}
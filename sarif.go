@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+)
+
+// sarifRuleID is the rule of every result, which are all signature matches
+const sarifRuleID = "signature-match"
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver struct {
+		Name           string      `json:"name"`
+		InformationURI string      `json:"informationUri"`
+		Rules          []sarifRule `json:"rules"`
+	} `json:"driver"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties struct {
+		Distance float64 `json:"distance"`
+		Query    string  `json:"query,omitempty"`
+	} `json:"properties"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			StartLine   int `json:"startLine"`
+			StartColumn int `json:"startColumn"`
+			EndLine     int `json:"endLine,omitempty"`
+			EndColumn   int `json:"endColumn,omitempty"`
+			ByteOffset  int `json:"byteOffset"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+// sarifOutput writes the results as a SARIF log (for code scanning
+// dashboards) once all of them are known
+type sarifOutput struct {
+	w       io.Writer
+	results []sarifResult
+}
+
+func (o *sarifOutput) write(f FuncWithDistance) error {
+	r := newResult(f)
+
+	sr := sarifResult{RuleID: sarifRuleID, Level: "note", Message: sarifMessage{describe(f.Func)}}
+	sr.Properties.Distance, sr.Properties.Query = r.Distance, r.Query
+
+	var loc sarifLocation
+	loc.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(r.Path)
+	loc.PhysicalLocation.Region.StartLine = r.Line
+	loc.PhysicalLocation.Region.StartColumn = r.Column
+	loc.PhysicalLocation.Region.EndLine = r.EndLine
+	loc.PhysicalLocation.Region.EndColumn = r.EndColumn
+	loc.PhysicalLocation.Region.ByteOffset = r.Offset
+	sr.Locations = []sarifLocation{loc}
+
+	o.results = append(o.results, sr)
+	return nil
+}
+
+func (o *sarifOutput) close() error {
+	var tool sarifTool
+	tool.Driver.Name = "glee"
+	tool.Driver.InformationURI = "https://github.com/meain/glee"
+	tool.Driver.Rules = []sarifRule{{sarifRuleID, sarifMessage{"Function matching the signature query"}}}
+
+	results := o.results
+	if results == nil {
+		results = []sarifResult{}
+	}
+
+	enc := json.NewEncoder(o.w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{{Tool: tool, Results: results}},
+	})
+}
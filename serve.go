@@ -0,0 +1,161 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// serveOptions are the options of `glee serve`
+type serveOptions struct {
+	addr   string
+	watch  bool
+	source sourceOptions
+}
+
+// serveFlags defines the flags of `glee serve` that set o
+func serveFlags(o *serveOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.StringVar(&o.addr, "addr", "localhost:7117", "address to listen on")
+	fs.BoolVar(&o.watch, "watch", true, "parse the files again when they change")
+	sourceFlags(fs, &o.source)
+	fs.BoolVar(&o.source.noCache, "no-cache", false, "parse all the files again instead of using the cached functions")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s serve [OPTIONS] [path...]\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(os.Stderr, "Keep the functions in memory and answer searches over HTTP")
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// server answers the searches of `glee serve` with the functions parsed
+// when it started (or when the files last changed)
+type server struct {
+	mu    sync.RWMutex
+	funcs []Func
+}
+
+// serve is `glee serve`, which parses the files once and then answers
+// `GET /search?q=QUERY` with the results as JSON lines (like -o jsonl),
+// so that editors and other tools do not have to parse the files for
+// every search. `match`, `limit` and `threshold` can be set in the query
+// string too.
+func serve(args []string) error {
+	o := serveOptions{}
+	fs := serveFlags(&o)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	roots := fs.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	if err := loadQueryAliases(defaultAliasFile()); err != nil {
+		return err
+	}
+	cache := openCache(o.source.noCache)
+	var dirs []string // the directories to watch with -watch
+	if o.watch {
+		o.source.walk.Dirs = func(path string) { dirs = append(dirs, path) }
+	}
+	_, funcs, err := o.source.load(roots, cache)
+	o.source.walk.Dirs = nil
+	if err != nil {
+		return err
+	}
+	s := &server{funcs: funcs}
+
+	if o.watch {
+		go func() {
+			err := watch(roots, dirs, func() error {
+				files, err := findAllFiles(roots, o.source.walk)
+				if err != nil {
+					return err
+				}
+				funcs, err := loadFuncs(files, nil, true, o.source.jobs, cache, nil)
+				if err != nil {
+					return err
+				}
+				s.mu.Lock()
+				s.funcs = funcs
+				s.mu.Unlock()
+				debugf(1, "reloaded %d functions", len(funcs))
+				return nil
+			})
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "glee: not watching the files: %s\n", err)
+			}
+		}()
+	}
+
+	http.HandleFunc("/search", s.search)
+	if !o.source.quiet {
+		fmt.Fprintf(os.Stderr, "serving %d functions on http://%s/search?q=QUERY\n", len(funcs), o.addr)
+	}
+	return http.ListenAndServe(o.addr, nil)
+}
+
+// search answers a search of `glee serve`
+func (s *server) search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	queries, _ := splitQueries(q["q"], true, false, false)
+	if len(queries) == 0 {
+		http.Error(w, "no query, eg: /search?q=(string)->(int)", http.StatusBadRequest)
+		return
+	}
+
+	match := "default"
+	if m := q.Get("match"); m != "" {
+		match = m
+	}
+	// -match types needs the packages loaded with the files
+	if !slices.Contains(matchModes, match) || match == "types" {
+		http.Error(w, fmt.Sprintf("invalid match type '%s'", match), http.StatusBadRequest)
+		return
+	}
+
+	po := printOptions{Format: "jsonl", Limit: 20, Threshold: 20}
+	if l := q.Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid limit '%s'", l), http.StatusBadRequest)
+			return
+		}
+		po.Limit = limit
+	}
+	if t := q.Get("threshold"); t != "" {
+		threshold, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid threshold '%s'", t), http.StatusBadRequest)
+			return
+		}
+		po.Threshold = threshold
+	}
+
+	s.mu.RLock()
+	funcs := s.funcs
+	s.mu.RUnlock()
+
+	opts := searchOptions{Normalize: true, Typos: 1, PreferExported: true, Weights: defaultWeights, Quiet: true}
+	results := [][]FuncWithDistance{}
+	for _, query := range queries {
+		fwd, err := search(funcs, query, match, false, opts, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		results = append(results, fwd)
+	}
+
+	w.Header().Set("Content-Type", "application/jsonl")
+	if err := printResults(mergeResults(queries, results), po, w); err != nil {
+		debugf(1, "search %q: %s", queries, err)
+	}
+}
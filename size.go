@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxFileSize skips minified bundles and generated files, which
+// take long to parse and are rarely what is searched for
+const defaultMaxFileSize = 1 << 20
+
+// fileSize is the value of -max-filesize, a number of bytes with an
+// optional K, M or G suffix (eg: 2M)
+type fileSize int64
+
+var sizeSuffixes = map[string]int64{"": 1, "K": 1 << 10, "M": 1 << 20, "G": 1 << 30}
+
+func (s *fileSize) String() string {
+	for _, suffix := range []string{"G", "M", "K"} {
+		if n := sizeSuffixes[suffix]; *s != 0 && int64(*s)%n == 0 {
+			return fmt.Sprintf("%d%s", int64(*s)/n, suffix)
+		}
+	}
+	return strconv.FormatInt(int64(*s), 10)
+}
+
+func (s *fileSize) Set(value string) error {
+	number := strings.TrimRight(strings.ToUpper(value), "KMGB")
+	suffix := strings.TrimSuffix(strings.ToUpper(value)[len(number):], "B")
+	n, err := strconv.ParseInt(number, 10, 64)
+	if _, ok := sizeSuffixes[suffix]; err != nil || !ok || n < 0 {
+		return fmt.Errorf("expected a size like 512K or 2M")
+	}
+	*s = fileSize(n * sizeSuffixes[suffix])
+	return nil
+}
+
+// tooLarge checks if the file at path is over max bytes (if max is not
+// 0), where info is from os.Lstat
+func tooLarge(path string, info os.FileInfo, max int64) bool {
+	if max == 0 {
+		return false
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Stat(path)
+		if err != nil {
+			return false
+		}
+		info = target
+	}
+	return info.Size() > max
+}
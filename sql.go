@@ -0,0 +1,185 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// sqlCreate matches the start of a function or procedure definition
+	// up to its name, eg: `CREATE OR REPLACE FUNCTION public.add`
+	sqlCreate = regexp.MustCompile(`(?i)\bCREATE\s+(?:OR\s+(?:REPLACE|ALTER)\s+)?(?:DEFINER\s*=\s*\S+\s+)?(?:TEMP(?:ORARY)?\s+)?(FUNCTION|PROCEDURE|PROC)\s+(?:IF\s+NOT\s+EXISTS\s+)?((?:[\w@#$]+|"[^"]*"|` + "`[^`]*`" + `|\[[^\]]*\])(?:\s*\.\s*(?:[\w@#$]+|"[^"]*"|` + "`[^`]*`" + `|\[[^\]]*\]))*)`)
+
+	// sqlReturns matches the RETURNS clause up to the type
+	sqlReturns = regexp.MustCompile(`(?i)^\s*RETURNS\s+`)
+
+	// sqlClauseEnd matches the clauses that can follow the parameters
+	// or the return type of a function
+	sqlClauseEnd = regexp.MustCompile(`(?i)\b(?:AS|IS|LANGUAGE|BEGIN|RETURN|IMMUTABLE|STABLE|VOLATILE|STRICT|CALLED|SECURITY|PARALLEL|COST|ROWS|SET|WITH|DETERMINISTIC|NOT|NO|READS|MODIFIES|CONTAINS|COMMENT|EXTERNAL|WINDOW|LEAKPROOF|SUPPORT|TRANSFORM)\b|;`)
+
+	// sqlDefault matches the start of a default value of a parameter
+	sqlDefault = regexp.MustCompile(`(?i)\s*(?:\bDEFAULT\b|=|:=).*$`)
+
+	// sqlBlock matches the words opening and closing blocks (with the
+	// word after END as `END IF` closes an IF that is not counted) and
+	// what ends a statement
+	sqlBlock = regexp.MustCompile(`(?im)\b(?:BEGIN|CASE|END(?:\s+(IF|LOOP|WHILE|REPEAT|FOR)\b)?|CREATE)\b|;|^[ \t]*GO[ \t]*$`)
+
+	// sqlDollarQuote matches the start of a dollar quoted string, eg: `$body$`
+	sqlDollarQuote = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+)
+
+// sqlModes are parameter modes, OUT parameters are outputs while INOUT
+// and (T-SQL) OUTPUT parameters are both inputs and outputs
+var sqlModes = map[string]bool{"IN": true, "OUT": true, "INOUT": true, "VARIADIC": true, "OUTPUT": true, "READONLY": true}
+
+// parseSQL finds `CREATE FUNCTION` and `CREATE PROCEDURE` statements in
+// SQL files. The tree-sitter grammar for SQL we have does not know
+// about procedures and most dialects, but the signatures are easy to
+// find without it. Parameter types are the inputs and the RETURNS
+// clause (or OUT parameters) the outputs.
+func parseSQL(sourceCode []byte, path string) []Func {
+	funcs := []Func{}
+	src := blankCommentsAndStrings(string(sourceCode), sqlSyntax)
+	lines := strings.Split(string(sourceCode), "\n")
+
+	for _, m := range sqlCreate.FindAllStringSubmatchIndex(src, -1) {
+		f := Func{
+			Path: path,
+			Loc:  position(sourceCode, m[0]),
+			Name: strings.NewReplacer(`"`, "", "`", "", "[", "", "]", "", " ", "").Replace(src[m[4]:m[5]]),
+			Args: []string{},
+			Rets: []string{},
+		}
+		f.Doc = commentAbove(lines, f.Loc[0], []string{"--"}, "/*", "*/")
+		f.BodyLoc = bodyRows(sourceCode, m[0], sqlStatementEnd(src, m[1]))
+
+		// the parameters of T-SQL procedures are not in parens
+		rest := src[m[1]:]
+		params := ""
+		if trimmed := strings.TrimLeft(rest, " \t\r\n"); strings.HasPrefix(trimmed, "(") {
+			open := len(rest) - len(trimmed)
+			end := matchingBracket(rest, open)
+			if end == -1 {
+				continue
+			}
+			params = rest[open+1 : end]
+			rest = rest[end+1:]
+		} else if end := sqlClauseEnd.FindStringIndex(rest); end != nil {
+			params = rest[:end[0]]
+			rest = rest[end[0]:]
+		}
+
+		outs := []string{}
+		for _, p := range splitTopLevel(params, ",") {
+			t, out, ok := sqlParam(p)
+			if !ok {
+				continue
+			}
+			if out != "OUT" {
+				f.Args = append(f.Args, t)
+			}
+			if out != "" {
+				outs = append(outs, t)
+			}
+		}
+
+		if r := sqlReturns.FindStringIndex(rest); r != nil {
+			rest = rest[r[1]:]
+			end := sqlClauseEnd.FindStringIndex(rest)
+
+			// the columns of `RETURNS TABLE (...)` can contain anything
+			if t := strings.TrimLeft(rest, " \t\r\n"); strings.HasPrefix(strings.ToUpper(t), "TABLE") {
+				if open := strings.Index(rest, "("); open != -1 && (end == nil || open < end[0]) {
+					if close := matchingBracket(rest, open); close != -1 {
+						end = []int{close + 1}
+					}
+				}
+			}
+
+			if end != nil {
+				f.Rets = []string{strings.Join(strings.Fields(rest[:end[0]]), " ")}
+			}
+		} else {
+			f.Rets = outs
+		}
+
+		funcs = append(funcs, f)
+	}
+
+	return funcs
+}
+
+// sqlStatementEnd returns the offset right after the `;` ending the
+// statement that contains from, skipping the ones in BEGIN ... END
+// blocks (the bodies in strings are blanked). Without a `;` (in T-SQL)
+// it ends before the next statement or `GO`.
+func sqlStatementEnd(src string, from int) int {
+	depth := 0
+	for _, m := range sqlBlock.FindAllStringSubmatchIndex(src[from:], -1) {
+		word := strings.ToUpper(src[from+m[0] : from+m[1]])
+		switch {
+		case word == ";":
+			if depth <= 0 {
+				return from + m[1]
+			}
+		case word == "CREATE" || strings.TrimSpace(word) == "GO":
+			if depth <= 0 {
+				return from + len(strings.TrimRight(src[from:from+m[0]], " \t\r\n"))
+			}
+		case word == "BEGIN" || word == "CASE":
+			depth++
+		case m[2] == -1: // END
+			depth--
+		}
+	}
+	return len(strings.TrimRight(src, " \t\r\n"))
+}
+
+// sqlParam returns the type of a parameter like `IN user_id bigint`
+// or `@id INT = 0 OUTPUT` along with its mode if it is an output
+func sqlParam(p string) (string, string, bool) {
+	fields := strings.Fields(p)
+	mode := ""
+
+	// T-SQL modes come after the default value
+	for len(fields) > 0 && sqlModes[strings.ToUpper(fields[len(fields)-1])] {
+		mode = strings.ToUpper(fields[len(fields)-1])
+		fields = fields[:len(fields)-1]
+	}
+
+	fields = strings.Fields(sqlDefault.ReplaceAllString(strings.Join(fields, " "), ""))
+	for len(fields) > 0 && sqlModes[strings.ToUpper(fields[0])] {
+		mode = strings.ToUpper(fields[0])
+		fields = fields[1:]
+	}
+
+	switch {
+	case len(fields) == 0:
+		return "", "", false
+	case len(fields) > 1 && !strings.Contains(fields[0], "("):
+		fields = fields[1:] // the name
+	}
+
+	if mode != "OUT" && mode != "OUTPUT" && mode != "INOUT" {
+		mode = ""
+	}
+	return strings.Join(fields, " "), mode, true
+}
+
+// sqlSyntax are the comments, strings and dollar quoted bodies of SQL
+var sqlSyntax = lexicalSyntax{
+	lineComments:  []string{"--"},
+	blockComments: [][2]string{{"/*", "*/"}},
+	quotes:        []quoteSyntax{{open: "'", close: "'", doubled: true}},
+	literal: func(src string) int {
+		tag := sqlDollarQuote.FindString(src)
+		if tag == "" {
+			return 0
+		}
+		if end := strings.Index(src[len(tag):], tag); end != -1 {
+			return len(tag) + end + len(tag)
+		}
+		return len(src)
+	},
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// earlyResults prints the results found while the files are still being
+// parsed with -stream, as soon as they are among the po.Limit closest
+// ones so far (and within po.Threshold). They are only a preview on
+// stderr, the sorted results are printed once all the files are parsed.
+type earlyResults struct {
+	out  output
+	po   printOptions
+	best []float64 // distances of the closest results so far, up to po.Limit
+	seen map[string]bool
+}
+
+func newEarlyResults(w io.Writer, po printOptions) (*earlyResults, error) {
+	po.GroupBy = "" // there is nothing to group yet
+	if !stderrIsTerminal() {
+		po.Color, po.Link = false, nil
+	}
+	out, err := getOutput(po, w)
+	if err != nil {
+		return nil, err
+	}
+	return &earlyResults{out: out, po: po, seen: map[string]bool{}}, nil
+}
+
+// add prints the results in fwd (the functions of a file that was just
+// parsed) that are closer than the ones printed so far
+func (e *earlyResults) add(fwd []FuncWithDistance) error {
+	for _, f := range fwd {
+		key := fmt.Sprintf("%s:%v", f.Func.Path, f.Func.Loc)
+		if f.Distance > e.po.Threshold || e.seen[key] || !e.better(f.Distance) {
+			continue
+		}
+		e.seen[key] = true
+		if err := e.out.write(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// better checks if distance is among the po.Limit closest ones so far,
+// keeping it if it is
+func (e *earlyResults) better(distance float64) bool {
+	if e.po.Limit == 0 {
+		return false
+	}
+	i := sort.Search(len(e.best), func(i int) bool { return e.best[i] > distance })
+	if e.po.Limit > 0 && i >= e.po.Limit {
+		return false
+	}
+	e.best = append(e.best, 0)
+	copy(e.best[i+1:], e.best[i:])
+	e.best[i] = distance
+	if e.po.Limit > 0 && len(e.best) > e.po.Limit {
+		e.best = e.best[:e.po.Limit]
+	}
+	return true
+}
+
+func (e *earlyResults) close() error {
+	return e.out.close()
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// tagsOptions are the options of `glee tags`
+type tagsOptions struct {
+	etags  bool
+	output string
+	source sourceOptions
+}
+
+// tagsFlags defines the flags of `glee tags` (also used for the
+// completions) that set o
+func tagsFlags(o *tagsOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet("tags", flag.ExitOnError)
+	fs.BoolVar(&o.etags, "e", false, "write an etags (emacs) TAGS file instead of a ctags one")
+	fs.StringVar(&o.output, "f", "", `file to write the tags to, "-" for stdout (default "tags", or "TAGS" with -e)`)
+	sourceFlags(fs, &o.source)
+	fs.BoolVar(&o.source.noCache, "no-cache", false, "parse all the files again instead of using the cached functions")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s tags [OPTIONS] [path...]\n", filepath.Base(os.Args[0]))
+		fmt.Fprintln(os.Stderr, "Write the functions to a tags file")
+		fmt.Fprintln(os.Stderr, "\nOptions:")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// tags is `glee tags`, which writes all the functions to a tags file
+// for the tag support of editors (ctags, or etags with -e)
+func tags(args []string) error {
+	o := tagsOptions{}
+	fs := tagsFlags(&o)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	roots := fs.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	_, funcs, err := o.source.load(roots, openCache(o.source.noCache))
+	if err != nil {
+		return err
+	}
+
+	if o.output == "" {
+		o.output = "tags"
+		if o.etags {
+			o.output = "TAGS"
+		}
+	}
+
+	w := io.Writer(os.Stdout)
+	if o.output != "-" {
+		file, err := os.Create(o.output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	bw := bufio.NewWriter(w)
+	if o.etags {
+		err = writeEtags(bw, funcs)
+	} else {
+		err = writeCtags(bw, funcs)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeCtags writes the functions in the extended ctags format, sorted
+// by name so that editors can binary search them
+func writeCtags(w io.Writer, funcs []Func) error {
+	sort.SliceStable(funcs, func(i, j int) bool {
+		if funcs[i].Name != funcs[j].Name {
+			return funcs[i].Name < funcs[j].Name
+		}
+		if funcs[i].Path != funcs[j].Path {
+			return funcs[i].Path < funcs[j].Path
+		}
+		return funcs[i].Loc[0] < funcs[j].Loc[0]
+	})
+
+	fmt.Fprintln(w, "!_TAG_FILE_FORMAT\t2\t/extended format/")
+	fmt.Fprintln(w, "!_TAG_FILE_SORTED\t1\t/0=unsorted, 1=sorted, 2=foldcase/")
+	fmt.Fprintln(w, "!_TAG_PROGRAM_NAME\tglee\t//")
+	fmt.Fprintln(w, "!_TAG_PROGRAM_URL\thttps://github.com/meain/glee\t//")
+
+	noTabs := strings.NewReplacer("\t", " ", "\n", " ")
+	for _, f := range funcs {
+		kind, scope := "f", ""
+		if f.Receiver != "" {
+			kind, scope = "m", "\tclass:"+noTabs.Replace(strings.TrimLeft(f.Receiver, "*&"))
+		}
+		signature := fmt.Sprintf("(%s) -> (%s)", strings.Join(f.LabelledArgs(), ", "), strings.Join(f.Rets, ", "))
+		_, err := fmt.Fprintf(w, "%s\t%s\t%d;\"\t%s\tline:%d%s\tsignature:%s\n",
+			f.Name, filepath.ToSlash(f.Path), f.Loc[0]+1, kind, f.Loc[0]+1, scope, noTabs.Replace(signature))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeEtags writes the functions in the etags format, a section per
+// file with the start of the line of each function up to its name
+func writeEtags(w io.Writer, funcs []Func) error {
+	paths := []string{}
+	byPath := map[string][]Func{}
+	for _, f := range funcs {
+		if _, ok := byPath[f.Path]; !ok {
+			paths = append(paths, f.Path)
+		}
+		byPath[f.Path] = append(byPath[f.Path], f)
+	}
+
+	for _, path := range paths {
+		sourceCode, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		// byte offset of the start of every line
+		offsets := []int{0}
+		for i, c := range sourceCode {
+			if c == '\n' {
+				offsets = append(offsets, i+1)
+			}
+		}
+
+		var section bytes.Buffer
+		for _, f := range byPath[path] {
+			row := f.Loc[0]
+			if row >= len(offsets) {
+				continue
+			}
+			line, _, _ := bytes.Cut(sourceCode[offsets[row]:], []byte("\n"))
+			if i := bytes.Index(line, []byte(f.Name)); i >= 0 {
+				line = line[:i+len(f.Name)]
+			}
+			fmt.Fprintf(&section, "%s\x7f%s\x01%d,%d\n", bytes.TrimRight(line, "\r"), f.Name, row+1, offsets[row])
+		}
+
+		if _, err := fmt.Fprintf(w, "\x0c\n%s,%d\n%s", filepath.ToSlash(path), section.Len(), section.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import "regexp"
+
+var identifier = regexp.MustCompile(`[A-Za-z_$][\w$]*`)
+
+// usages is how often every name is referenced in the sources, as a
+// rough measure of how widely used a function is. Every identifier
+// (including the ones in comments and strings) counts, except for the
+// definitions of the functions.
+type usages map[string]int
+
+// addReferences counts the identifiers in sourceCode
+func (u usages) addReferences(sourceCode []byte) {
+	for _, id := range identifier.FindAll(sourceCode, -1) {
+		u[string(id)]++
+	}
+}
+
+// removeDefinitions drops the names of funcs from the counts once
+func (u usages) removeDefinitions(funcs []Func) {
+	for _, f := range funcs {
+		if u[f.Name] > 0 {
+			u[f.Name]--
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+// verbosity is how much glee logs about what it does on stderr: 1 for
+// the skipped files, the queries and how long the steps take, 2 also for
+// every file parsed
+type verbosity int
+
+// verbose is the verbosity set with -v or -vv
+var verbose verbosity
+
+// logger writes the logs of -v, apart from the results on stdout
+var logger = log.New(os.Stderr, "glee: ", log.Ltime|log.Lmicroseconds)
+
+func (v *verbosity) String() string { return strconv.Itoa(int(*v)) }
+
+func (v *verbosity) Set(s string) error {
+	if s == "true" {
+		*v++
+		return nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return fmt.Errorf("expected a level (eg: -v=2)")
+	}
+	*v = verbosity(n)
+	return nil
+}
+
+func (v *verbosity) IsBoolFlag() bool { return true }
+
+// verboseFlags defines -v and -vv in fs
+func verboseFlags(fs *flag.FlagSet) {
+	fs.Var(&verbose, "v", "log the skipped files, the queries and how long every step takes on stderr (-vv or -v=2 also logs every file)")
+	fs.BoolFunc("vv", "same as -v=2", func(string) error { verbose = 2; return nil })
+}
+
+// debugf logs with -v if the verbosity is at least level
+func debugf(level verbosity, format string, v ...any) {
+	if verbose >= level {
+		logger.Printf(format, v...)
+	}
+}
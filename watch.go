@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDelay is how long to wait for more changes before searching
+// again, as editors and git often write several files at once
+const watchDelay = 200 * time.Millisecond
+
+// watch runs search again every time the source files in roots (or in
+// dirs, the directories that were searched) change, until glee is
+// interrupted. The files that did not change are not parsed again
+// thanks to the cache.
+func watch(roots, dirs []string, search func() error) error {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	watched := map[string]bool{}
+	for _, root := range roots {
+		if info, err := os.Stat(root); err == nil && !info.IsDir() {
+			root = filepath.Dir(root)
+		}
+		watched[root] = true
+	}
+	// all the directories that were walked, even the ones without
+	// source files yet, while the ignored ones like node_modules are
+	// left out
+	for _, dir := range dirs {
+		watched[dir] = true
+	}
+	for dir := range watched {
+		if err := w.Add(dir); err != nil {
+			debugf(1, "not watching %s: %s", dir, err)
+		}
+	}
+	debugf(1, "watching %d directories", len(watched))
+
+	timer := time.NewTimer(watchDelay)
+	timer.Stop()
+	for {
+		select {
+		case event, ok := <-w.Events:
+			if !ok {
+				return nil
+			}
+			if !sourceChanged(event) {
+				continue
+			}
+			debugf(2, "%s", event)
+			if event.Has(fsnotify.Create) {
+				watchNewDir(w, event.Name)
+			}
+			timer.Reset(watchDelay)
+		case err, ok := <-w.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+		case <-timer.C:
+			if err := search(); err != nil {
+				fmt.Fprintf(os.Stderr, "glee: %s\n", err)
+			}
+		}
+	}
+}
+
+// sourceChanged checks if event can change the results, the files that
+// are not source files (eg: the swap files of editors) are left out
+func sourceChanged(event fsnotify.Event) bool {
+	if event.Op == fsnotify.Chmod {
+		return false
+	}
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		return true // it could have been a directory
+	}
+	info, err := os.Stat(event.Name)
+	if err != nil {
+		return false
+	}
+	return info.IsDir() || getLanguage(event.Name) != ""
+}
+
+// watchNewDir watches path along with the directories in it if it is a
+// directory that was created (or moved in) while watching
+func watchNewDir(w *fsnotify.Watcher, path string) {
+	filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if d.Name()[0] == '.' {
+			return filepath.SkipDir
+		}
+		if err := w.Add(path); err != nil {
+			debugf(1, "not watching %s: %s", path, err)
+		}
+		return nil
+	})
+}